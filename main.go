@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"shiplang/src/ast"
+	"shiplang/src/checker"
 	"shiplang/src/lexer"
 	"shiplang/src/parser"
 	"shiplang/src/runtime"
@@ -65,20 +66,41 @@ func loadAST(filename string) (ast.Stmt, error) {
 	return encodedAST.Stmt, nil
 }
 
+// reportTypeErrors prints the errors the checker finds in program and
+// returns whether there were any.
+func reportTypeErrors(filename string, program ast.BlockStmt, strict bool) bool {
+	errors := checker.Check(program, strict)
+	for _, err := range errors {
+		fmt.Printf("%s:%d:%d: %s\n", filename, err.Line, err.Column, err.Message)
+	}
+	return len(errors) > 0
+}
+
 func main() {
 
 	dumpTokens := flag.Bool("tokens", false, "Dump generated tokens")
 	dumpAST := flag.Bool("ast", false, "Dump generated AST")
 	dumpEnv := flag.Bool("env", false, "Dump generated environment")
 	makeRunnable := flag.Bool("runnable", false, "Creates a runnable AST")
-
-	flag.Parse()
+	strict := flag.Bool("strict", false, "Enable null safety for every file")
+	typeCheck := flag.Bool("check", false, "Type check the program before running it")
+
+	// `check <filename>` only type checks the program
+	args := os.Args[1:]
+	checkOnly := len(args) > 0 && args[0] == "check"
+	if checkOnly {
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	if flag.NArg() == 0 {
-		fmt.Println("Usage: go run main.go [--tokens] [--ast] [--env] [--runnable] <filename>")
+		fmt.Println("Usage: go run main.go [--tokens] [--ast] [--env] [--runnable] [--strict] [--check] <filename>")
+		fmt.Println("       go run main.go check [--strict] <filename>")
 		os.Exit(1)
 	}
 
+	runtime.SetStrict(*strict)
+
 	filename := flag.Arg(0)
 
 	if strings.HasSuffix(filename, ".spr") {
@@ -88,6 +110,15 @@ func main() {
 			fmt.Printf("Error loading file: %v\n", err)
 			os.Exit(1)
 		}
+		if program, ok := loadedAst.(ast.BlockStmt); ok && (checkOnly || *typeCheck) {
+			if reportTypeErrors(filename, program, *strict) {
+				os.Exit(1)
+			}
+		}
+		if checkOnly {
+			return
+		}
+
 		env := runtime.NewEnv(nil)
 		runtime.Evaluate(loadedAst, env)
 	} else {
@@ -100,6 +131,17 @@ func main() {
 
 		tokens := lexer.Tokenize(string(bytes))
 		parsedAst := parser.Parse(tokens)
+
+		if checkOnly || *typeCheck {
+			if reportTypeErrors(filename, parsedAst, *strict) {
+				os.Exit(1)
+			}
+			if checkOnly {
+				fmt.Println("No type errors found in", filename)
+				return
+			}
+		}
+
 		env := runtime.NewEnv(nil)
 
 		if *makeRunnable {
@@ -14,6 +14,10 @@ type Type interface {
 	_type()
 }
 
+type Pattern interface {
+	pattern()
+}
+
 func init() {
 	gob.Register(NumberExpr{})
 	gob.Register(StringExpr{})
@@ -24,9 +28,18 @@ func init() {
 	gob.Register(AssignmentExpr{})
 	gob.Register(StructInstantiationExpr{})
 	gob.Register(ArrayInstantiationExpr{})
+	gob.Register(MapInstantiationExpr{})
+	gob.Register(MapEntry{})
+	gob.Register(SetInstantiationExpr{})
+	gob.Register(TupleExpr{})
 	gob.Register(MemberAccessExpr{})
 	gob.Register(ArrayAccessExpr{})
+	gob.Register(SliceExpr{})
 	gob.Register(CallExpr{})
+	gob.Register(NamedArgumentExpr{})
+	gob.Register(SpreadExpr{})
+	gob.Register(MatchExpr{})
+	gob.Register(MatchArm{})
 
 	gob.Register(BlockStmt{})
 	gob.Register(ExpressionStmt{})
@@ -37,11 +50,33 @@ func init() {
 	gob.Register(ReturnStmt{})
 	gob.Register(BreakStmt{})
 	gob.Register(IfStmt{})
+	gob.Register(SwitchStmt{})
 	gob.Register(WhileStmt{})
+	gob.Register(DoWhileStmt{})
+	gob.Register(LoopStmt{})
 	gob.Register(ForeachStmt{})
 	gob.Register(ForStmt{})
 	gob.Register(ImportStmt{})
+	gob.Register(EnumDeclStmt{})
+	gob.Register(TypeDeclStmt{})
+	gob.Register(EnumVariant{})
 
 	gob.Register(SymbolType{})
+	gob.Register(GenericType{})
 	gob.Register(ArrayType{})
+	gob.Register(MapType{})
+	gob.Register(SetType{})
+	gob.Register(TupleType{})
+	gob.Register(NullableType{})
+	gob.Register(UnionType{})
+	gob.Register(FnType{})
+
+	gob.Register(WildcardPattern{})
+	gob.Register(LiteralPattern{})
+	gob.Register(BindingPattern{})
+	gob.Register(VariantPattern{})
+	gob.Register(TuplePattern{})
+	gob.Register(ArrayPattern{})
+	gob.Register(StructPattern{})
+	gob.Register(StructFieldPattern{})
 }
@@ -48,6 +48,9 @@ func (n AssignmentExpr) expr() {}
 type StructInstantiationExpr struct {
 	StructName string
 	Properties map[string]Expr
+	Order      []string // property names, in the order they are written
+	Line       int
+	Column     int
 }
 
 func (n StructInstantiationExpr) expr() {}
@@ -55,13 +58,43 @@ func (n StructInstantiationExpr) expr() {}
 type ArrayInstantiationExpr struct {
 	Underlying Type
 	Contents   []Expr
+	Line       int
+	Column     int
 }
 
 func (n ArrayInstantiationExpr) expr() {}
 
+type MapEntry struct {
+	Key   Expr
+	Value Expr
+}
+
+type MapInstantiationExpr struct {
+	KeyType   Type
+	ValueType Type
+	Entries   []MapEntry
+}
+
+func (n MapInstantiationExpr) expr() {}
+
+type SetInstantiationExpr struct {
+	Underlying Type
+	Contents   []Expr
+}
+
+func (n SetInstantiationExpr) expr() {}
+
+type TupleExpr struct {
+	Elements []Expr
+}
+
+func (n TupleExpr) expr() {}
+
 type MemberAccessExpr struct {
 	Struct Expr
 	Member string
+	Line   int
+	Column int
 }
 
 func (n MemberAccessExpr) expr() {}
@@ -69,16 +102,57 @@ func (n MemberAccessExpr) expr() {}
 type ArrayAccessExpr struct {
 	Array Expr
 	Index Expr
-	Prev  bool
-	Rest  bool
 }
 
 func (n ArrayAccessExpr) expr() {}
 
+// a[start:end:step], every part is optional and nil when left out
+type SliceExpr struct {
+	Array Expr
+	Start Expr
+	End   Expr
+	Step  Expr
+}
+
+func (n SliceExpr) expr() {}
+
+// NamedArgumentExpr is a call argument written as `name: value`
+type NamedArgumentExpr struct {
+	Name  string
+	Value Expr
+}
+
+func (n NamedArgumentExpr) expr() {}
+
+// SpreadExpr expands an array or tuple into positional call arguments
+type SpreadExpr struct {
+	Value Expr
+}
+
+func (n SpreadExpr) expr() {}
+
 type CallExpr struct {
 	FunctionName string
 	Struct       Expr
+	Callee       Expr // set when calling the result of any other expression
 	Arguments    []Expr
+	Line         int
+	Column       int
 }
 
 func (n CallExpr) expr() {}
+
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expr
+	Body    Stmt
+}
+
+type MatchExpr struct {
+	Subject Expr
+	Arms    []MatchArm
+	Line    int
+	Column  int
+}
+
+func (n MatchExpr) expr() {}
@@ -0,0 +1,51 @@
+package ast
+
+type WildcardPattern struct{}
+
+func (p WildcardPattern) pattern() {}
+
+type LiteralPattern struct {
+	Value Expr
+}
+
+func (p LiteralPattern) pattern() {}
+
+type BindingPattern struct {
+	Name string
+}
+
+func (p BindingPattern) pattern() {}
+
+type VariantPattern struct {
+	EnumName string
+	Variant  string
+	Fields   []Pattern
+}
+
+func (p VariantPattern) pattern() {}
+
+type TuplePattern struct {
+	Elements []Pattern
+}
+
+func (p TuplePattern) pattern() {}
+
+type ArrayPattern struct {
+	Elements []Pattern
+	Rest     string
+	HasRest  bool
+}
+
+func (p ArrayPattern) pattern() {}
+
+type StructFieldPattern struct {
+	Name    string
+	Pattern Pattern
+}
+
+type StructPattern struct {
+	StructName string
+	Fields     []StructFieldPattern
+}
+
+func (p StructPattern) pattern() {}
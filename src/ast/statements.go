@@ -14,15 +14,23 @@ func (n ExpressionStmt) stmt() {}
 
 type VarDeclStmt struct {
 	VarName       string
+	Pattern       Pattern // set instead of VarName when destructuring
 	IsConstant    bool
 	AssignedValue Expr
 	ExplicitType  Type
+	Line          int
+	Column        int
+	Exported      bool
 }
 
 func (n VarDeclStmt) stmt() {}
 
 type StructProperty struct {
-	Type Type
+	Type     Type
+	Default  Expr // used when an instantiation leaves the property out
+	Required bool // the property must always be given
+	Public   bool // other modules may access the property
+	Readonly bool // the property can only be set when instantiating
 }
 
 type StructMethod struct {
@@ -31,16 +39,24 @@ type StructMethod struct {
 
 type StructDeclStmt struct {
 	StructName string
+	TypeParams []string
 	Properties map[string]StructProperty
+	Order      []string // property names, in declaration order
+	Embedded   []string // embedded structs, in declaration order
+	Line       int
+	Column     int
+	Exported   bool
 }
 
 func (n StructDeclStmt) stmt() {}
 
 type FnDeclStmt struct {
 	FnName     string
+	TypeParams []string
 	Parameters []Parameter
 	ReturnType Type
 	Body       BlockStmt
+	Exported   bool
 }
 
 func (n FnDeclStmt) stmt() {}
@@ -53,12 +69,16 @@ type ImplStmt struct {
 func (n ImplStmt) stmt() {}
 
 type Parameter struct {
-	Name string
-	Type Type
+	Name       string
+	Type       Type
+	Default    Expr
+	IsVariadic bool
 }
 
 type ReturnStmt struct {
-	Value Expr
+	Value  Expr
+	Line   int
+	Column int
 }
 
 func (n ReturnStmt) stmt() {}
@@ -67,15 +87,42 @@ type BreakStmt struct{}
 
 func (n BreakStmt) stmt() {}
 
+// ElifBody is an else if branch, branches are tried in source order
+type ElifBody struct {
+	Condition Expr
+	Body      BlockStmt
+}
+
 type IfStmt struct {
 	IfBody     BlockStmt
 	Condition  Expr
 	ElseBody   BlockStmt
-	ElifBodies map[Expr]BlockStmt
+	ElifBodies []ElifBody
 }
 
 func (i IfStmt) stmt() {}
 
+// CaseLabel is a single value, or an inclusive range lo..hi when Upper is set
+type CaseLabel struct {
+	Value Expr
+	Upper Expr
+}
+
+type SwitchCase struct {
+	Labels []CaseLabel
+	Body   BlockStmt
+}
+
+type SwitchStmt struct {
+	Subject Expr
+	Cases   []SwitchCase
+	Default BlockStmt
+	Line    int
+	Column  int
+}
+
+func (n SwitchStmt) stmt() {}
+
 type WhileStmt struct {
 	Body      BlockStmt
 	Condition Expr
@@ -83,14 +130,35 @@ type WhileStmt struct {
 
 func (i WhileStmt) stmt() {}
 
+// DoWhileStmt runs its body once before checking the condition
+type DoWhileStmt struct {
+	Body      BlockStmt
+	Condition Expr
+}
+
+func (i DoWhileStmt) stmt() {}
+
+// LoopStmt runs its body until a break or return
+type LoopStmt struct {
+	Body BlockStmt
+}
+
+func (i LoopStmt) stmt() {}
+
 type ForeachStmt struct {
-	Iterator   string
-	Collection Expr
-	Body       BlockStmt
+	KeyIterator  string
+	KeyType      Type // nil unless annotated
+	Iterator     string
+	IteratorType Type // nil unless annotated
+	Collection   Expr
+	Body         BlockStmt
+	Line         int
+	Column       int
 }
 
 func (i ForeachStmt) stmt() {}
 
+// ForStmt clauses are all optional, a missing Cond loops until a break
 type ForStmt struct {
 	Init Stmt
 	Cond Expr
@@ -106,3 +174,27 @@ type ImportStmt struct {
 }
 
 func (i ImportStmt) stmt() {}
+
+type EnumVariant struct {
+	Name   string
+	Fields []Parameter
+}
+
+type EnumDeclStmt struct {
+	EnumName string
+	Variants []EnumVariant
+	Exported bool
+}
+
+func (e EnumDeclStmt) stmt() {}
+
+// type UserId = number; declares an alias, type Email = distinct string;
+// declares a new type that only accepts explicitly converted values
+type TypeDeclStmt struct {
+	TypeName   string
+	Underlying Type
+	Distinct   bool
+	Exported   bool
+}
+
+func (t TypeDeclStmt) stmt() {}
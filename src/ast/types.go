@@ -6,8 +6,56 @@ type SymbolType struct {
 
 func (t SymbolType) _type() {}
 
+// GenericType is an instantiated generic struct such as Pair<number, string>
+type GenericType struct {
+	Name     string
+	TypeArgs []Type
+}
+
+func (t GenericType) _type() {}
+
 type ArrayType struct {
 	Underlying Type
 }
 
 func (t ArrayType) _type() {}
+
+type MapType struct {
+	Key   Type
+	Value Type
+}
+
+func (t MapType) _type() {}
+
+type SetType struct {
+	Underlying Type
+}
+
+func (t SetType) _type() {}
+
+type TupleType struct {
+	Elements []Type
+}
+
+func (t TupleType) _type() {}
+
+// NullableType is written ?T and also accepts null in strict mode
+type NullableType struct {
+	Underlying Type
+}
+
+func (t NullableType) _type() {}
+
+type UnionType struct {
+	Members []Type
+}
+
+func (t UnionType) _type() {}
+
+// FnType is written fn(number, string): boolean, the return type is optional
+type FnType struct {
+	Params []Type
+	Return Type
+}
+
+func (t FnType) _type() {}
@@ -0,0 +1,332 @@
+package checker
+
+import (
+	"fmt"
+	"shiplang/src/ast"
+	"shiplang/src/runtime"
+	"sort"
+)
+
+// Error is a type error found without running the program.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+type symbol struct {
+	Type     *runtime.TypeSpec // what is known about the value at this point
+	Declared *runtime.TypeSpec // annotation assignments are checked against
+	Constant bool
+}
+
+type param struct {
+	Name       string
+	Type       *runtime.TypeSpec
+	HasDefault bool
+	Variadic   bool
+}
+
+type signature struct {
+	Name   string
+	Params []param
+	Return *runtime.TypeSpec
+	// Positional is set for values of a fn type, whose parameters have no
+	// names that could be used as named arguments
+	Positional bool
+}
+
+type structInfo struct {
+	Name       string
+	TypeParams []string
+	Props      map[string]*runtime.TypeSpec
+	Order      []string        // property names, in declaration order
+	Defaults   map[string]bool // properties with a default value
+	Required   map[string]bool
+	Readonly   map[string]bool
+	Embedded   []string
+	Methods    map[string]*signature
+}
+
+type enumInfo struct {
+	Name     string
+	Variants []string                       // variant names, in declaration order
+	Fields   map[string][]*runtime.TypeSpec // field types, by variant
+}
+
+type scope struct {
+	parent  *scope
+	vars    map[string]*symbol
+	fns     map[string]*signature
+	structs map[string]*structInfo
+	enums   map[string]*enumInfo
+	aliases map[string]*runtime.TypeSpec
+	fn      *signature // set on the scope of a function body
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:  parent,
+		vars:    make(map[string]*symbol),
+		fns:     make(map[string]*signature),
+		structs: make(map[string]*structInfo),
+		enums:   make(map[string]*enumInfo),
+		aliases: make(map[string]*runtime.TypeSpec),
+	}
+}
+
+func (s *scope) lookupVar(name string) *symbol {
+	if sym, exists := s.vars[name]; exists {
+		return sym
+	}
+	if s.parent != nil {
+		return s.parent.lookupVar(name)
+	}
+	return nil
+}
+
+// lookupCallable mirrors the runtime, which looks for a variable first and
+// then for a declared function in each scope.
+func (s *scope) lookupCallable(name string) *signature {
+	if sig, exists := s.fns[name]; exists {
+		return sig
+	}
+	if sym, exists := s.vars[name]; exists {
+		return signatureOf(sym.Type)
+	}
+	if s.parent != nil {
+		return s.parent.lookupCallable(name)
+	}
+	return nil
+}
+
+// isNative reports whether name refers to the native function native, and
+// not to a declaration shadowing it.
+func (s *scope) isNative(native string, name string) bool {
+	if name != native {
+		return false
+	}
+	global := s
+	for global.parent != nil {
+		global = global.parent
+	}
+	return s.lookupCallable(name) == global.fns[native]
+}
+
+func (s *scope) lookupStruct(name string) *structInfo {
+	if info, exists := s.structs[name]; exists {
+		return info
+	}
+	if s.parent != nil {
+		return s.parent.lookupStruct(name)
+	}
+	return nil
+}
+
+func (s *scope) lookupEnum(name string) *enumInfo {
+	if info, exists := s.enums[name]; exists {
+		return info
+	}
+	if s.parent != nil {
+		return s.parent.lookupEnum(name)
+	}
+	return nil
+}
+
+// promoted finds the embedded struct providing member the way the runtime
+// does, shallower embeddings first. conflict describes an ambiguous member.
+func (s *scope) promoted(info *structInfo, member string) (owner *structInfo, conflict string) {
+	for _, embedded := range info.Embedded {
+		inner := s.lookupStruct(embedded)
+		if inner == nil {
+			continue
+		}
+		_, isProperty := inner.Props[member]
+		_, isMethod := inner.Methods[member]
+		if !isProperty && !isMethod {
+			continue
+		}
+		if owner != nil {
+			return nil, fmt.Sprintf("Struct %s embeds both %s and %s which define %s", info.Name, owner.Name, embedded, member)
+		}
+		owner = inner
+	}
+
+	if owner != nil {
+		return owner, ""
+	}
+
+	var via string
+	for _, embedded := range info.Embedded {
+		inner := s.lookupStruct(embedded)
+		if inner == nil {
+			continue
+		}
+		found, conflict := s.promoted(inner, member)
+		if conflict != "" {
+			return nil, conflict
+		}
+		if found == nil {
+			continue
+		}
+		if owner != nil {
+			return nil, fmt.Sprintf("Struct %s embeds both %s and %s which define %s", info.Name, via, embedded, member)
+		}
+		owner, via = found, embedded
+	}
+
+	return owner, ""
+}
+
+func (s *scope) enclosingFn() *signature {
+	if s.fn != nil {
+		return s.fn
+	}
+	if s.parent != nil {
+		return s.parent.enclosingFn()
+	}
+	return nil
+}
+
+func (s *scope) allAliases() map[string]*runtime.TypeSpec {
+	aliases := make(map[string]*runtime.TypeSpec)
+	if s.parent != nil {
+		aliases = s.parent.allAliases()
+	}
+	for name, t := range s.aliases {
+		aliases[name] = t
+	}
+	return aliases
+}
+
+// resolve converts a type annotation, expanding the aliases in scope.
+func (s *scope) resolve(t ast.Type) *runtime.TypeSpec {
+	return runtime.SubstituteType(runtime.TypeOf(t), s.allAliases())
+}
+
+// structOf finds the struct a value of type t is an instance of, along with
+// the bindings of its type parameters.
+func (s *scope) structOf(t *runtime.TypeSpec) (*structInfo, map[string]*runtime.TypeSpec) {
+	if t == nil || t.Kind != runtime.NamedKind {
+		return nil, nil
+	}
+
+	info := s.lookupStruct(t.Name)
+	if info == nil {
+		return nil, nil
+	}
+
+	bindings := make(map[string]*runtime.TypeSpec)
+	for i, param := range info.TypeParams {
+		bindings[param] = runtime.AnyType
+		if i < len(t.Args) {
+			bindings[param] = t.Args[i]
+		}
+	}
+	return info, bindings
+}
+
+// signatureOf describes a value of fn type so calls through it can be
+// checked.
+func signatureOf(t *runtime.TypeSpec) *signature {
+	if t == nil || t.Kind != runtime.FnKind {
+		return nil
+	}
+
+	sig := &signature{Return: t.Elem, Positional: true}
+	for i, arg := range t.Args {
+		argType := *arg
+		argType.Variadic = false
+		sig.Params = append(sig.Params, param{
+			Name:     fmt.Sprintf("#%d", i+1),
+			Type:     &argType,
+			Variadic: arg.Variadic,
+		})
+	}
+	return sig
+}
+
+type checker struct {
+	errors []Error
+	strict bool
+	line   int // position of the innermost node visited that carries one
+	column int
+}
+
+// Check reports the type errors in program without running it. Anything
+// whose type cannot be known statically is treated as any and accepted, so
+// every error reported is one the runtime would also run into.
+func Check(program ast.BlockStmt, strict bool) []Error {
+	c := &checker{strict: strict || len(program.Body) > 0 && runtime.IsStrictDirective(program.Body[0])}
+	global := newScope(nil)
+	declareNatives(global)
+
+	c.check_block(program.Body, global)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		if c.errors[i].Line != c.errors[j].Line {
+			return c.errors[i].Line < c.errors[j].Line
+		}
+		return c.errors[i].Column < c.errors[j].Column
+	})
+	return c.errors
+}
+
+func (c *checker) errorf(line, column int, format string, args ...any) {
+	if line == 0 {
+		line, column = c.line, c.column
+	}
+	c.errors = append(c.errors, Error{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+func known(t *runtime.TypeSpec) bool {
+	return t != nil && !t.Equal(runtime.AnyType)
+}
+
+// assignable accepts values of unknown type.
+func (c *checker) assignable(from *runtime.TypeSpec, to *runtime.TypeSpec) bool {
+	return !known(from) || runtime.IsAssignable(from, to, c.strict)
+}
+
+// mayBe reports whether a value of type t could be of type want at runtime,
+// which is what operators care about.
+func (c *checker) mayBe(t *runtime.TypeSpec, want *runtime.TypeSpec) bool {
+	return !known(t) || runtime.IsAssignable(want, t, c.strict)
+}
+
+func declareNatives(s *scope) {
+	s.vars["true"] = &symbol{Type: runtime.BooleanType, Declared: runtime.BooleanType, Constant: true}
+	s.vars["false"] = &symbol{Type: runtime.BooleanType, Declared: runtime.BooleanType, Constant: true}
+	s.vars["null"] = &symbol{Type: runtime.NullType, Declared: runtime.NullType, Constant: true}
+
+	s.fns["show"] = &signature{Name: "show", Params: []param{{Name: "values", Type: runtime.AnyType, Variadic: true}}, Return: runtime.NullType}
+	s.fns["ask"] = &signature{Name: "ask", Params: []param{{Name: "prompt", Type: runtime.StringType, HasDefault: true}}, Return: runtime.StringType}
+	s.fns["time"] = &signature{Name: "time", Return: runtime.NumberType}
+	s.fns["date"] = &signature{Name: "date", Return: runtime.StringType}
+	s.fns["freeze"] = &signature{Name: "freeze", Params: []param{{Name: "value", Type: runtime.AnyType}}, Return: runtime.AnyType}
+	s.fns["range"] = &signature{Name: "range", Params: []param{{Name: "start", Type: runtime.NumberType}, {Name: "end", Type: runtime.NumberType, HasDefault: true}}, Return: runtime.NamedType("array", runtime.NumberType)}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func withTypeArgs(name string, typeParams []string) *runtime.TypeSpec {
+	args := make([]*runtime.TypeSpec, len(typeParams))
+	for i := range args {
+		args[i] = runtime.AnyType
+	}
+	return runtime.NamedType(name, args...)
+}
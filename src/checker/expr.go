@@ -0,0 +1,621 @@
+package checker
+
+import (
+	"shiplang/src/ast"
+	"shiplang/src/lexer"
+	"shiplang/src/runtime"
+	"strings"
+)
+
+// check_expr reports the errors inside expr and returns its type, any when
+// it cannot be known without running the program.
+func (c *checker) check_expr(expr ast.Expr, s *scope) *runtime.TypeSpec {
+	switch e := expr.(type) {
+	case ast.NumberExpr:
+		return runtime.NumberType
+	case ast.StringExpr:
+		return runtime.StringType
+	case ast.SymbolExpr:
+		if sym := s.lookupVar(e.Value); sym != nil {
+			return sym.Type
+		}
+		if sig := s.lookupCallable(e.Value); sig != nil {
+			return fnType(sig)
+		}
+	case ast.PrefixExpr:
+		return c.check_prefix_expr(e, s)
+	case ast.BinaryExpr:
+		return c.check_binary_expr(e, s)
+	case ast.ArrayInstantiationExpr:
+		return c.check_array_inst_expr(e, s)
+	case ast.MapInstantiationExpr:
+		keyType, valueType := s.resolve(e.KeyType), s.resolve(e.ValueType)
+		for _, entry := range e.Entries {
+			if t := c.check_expr(entry.Key, s); !c.assignable(t, keyType) {
+				c.errorf(0, 0, "Map of %s keys cannot contain a key of type %s", keyType, t)
+			}
+			if t := c.check_expr(entry.Value, s); !c.assignable(t, valueType) {
+				c.errorf(0, 0, "Map of %s values cannot contain a value of type %s", valueType, t)
+			}
+		}
+		return runtime.NamedType(runtime.MapType.Name, keyType, valueType)
+	case ast.SetInstantiationExpr:
+		elementType := s.resolve(e.Underlying)
+		for _, element := range e.Contents {
+			if t := c.check_expr(element, s); !c.assignable(t, elementType) {
+				c.errorf(0, 0, "Set of %s cannot contain %s", elementType, t)
+			}
+		}
+		return runtime.NamedType(runtime.SetType.Name, elementType)
+	case ast.TupleExpr:
+		elements := make([]*runtime.TypeSpec, len(e.Elements))
+		for i, element := range e.Elements {
+			elements[i] = c.check_expr(element, s)
+		}
+		return runtime.NamedType(runtime.TupleType.Name, elements...)
+	case ast.ArrayAccessExpr:
+		return c.check_array_access_expr(e, s)
+	case ast.SliceExpr:
+		return c.check_slice_expr(e, s)
+	case ast.StructInstantiationExpr:
+		return c.check_struct_inst_expr(e, s)
+	case ast.CallExpr:
+		return c.check_call_expr(e, s)
+	case ast.MemberAccessExpr:
+		return c.check_member_access_expr(e, s)
+	case ast.AssignmentExpr:
+		return c.check_assignment_expr(e, s)
+	case ast.MatchExpr:
+		c.check_match_expr(e, s)
+	case ast.NamedArgumentExpr:
+		c.check_expr(e.Value, s)
+	case ast.SpreadExpr:
+		c.check_expr(e.Value, s)
+	}
+
+	return runtime.AnyType
+}
+
+func (c *checker) check_match_expr(e ast.MatchExpr, s *scope) {
+	subject := c.check_expr(e.Subject, s)
+
+	if info := matchedEnum(subject, e.Arms, s); info != nil {
+		if missing := runtime.MissingVariants(info.Name, info.Variants, e.Arms); len(missing) > 0 {
+			c.errorf(e.Line, e.Column, "Non-exhaustive match on %s: missing %s", info.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	for _, arm := range e.Arms {
+		armScope := newScope(s)
+		declarePattern(arm.Pattern, subject, armScope)
+		if arm.Guard != nil {
+			c.check_expr(arm.Guard, armScope)
+		}
+		c.check_stmt(arm.Body, armScope)
+	}
+}
+
+// matchedEnum finds the enum a match is over, from the type of its subject
+// or else from its arms when they only match variants of one enum, which
+// the subject then has to be for any arm to match.
+func matchedEnum(subject *runtime.TypeSpec, arms []ast.MatchArm, s *scope) *enumInfo {
+	if subject != nil && subject.Kind == runtime.NamedKind {
+		if info := s.lookupEnum(subject.Name); info != nil {
+			return info
+		}
+	}
+
+	enumName := ""
+	for _, arm := range arms {
+		variantPattern, ok := arm.Pattern.(ast.VariantPattern)
+		switch {
+		case ok && (enumName == "" || enumName == variantPattern.EnumName):
+			enumName = variantPattern.EnumName
+		case arm.Guard != nil || !runtime.IsIrrefutable(arm.Pattern):
+			return nil
+		}
+	}
+	return s.lookupEnum(enumName)
+}
+
+// declarePattern declares the bindings of a pattern matched against a
+// value of type t, those in the fields of a variant have the type of the
+// field and the others are unknown.
+func declarePattern(pattern ast.Pattern, t *runtime.TypeSpec, s *scope) {
+	switch p := pattern.(type) {
+	case ast.BindingPattern:
+		s.vars[p.Name] = &symbol{Type: t, Declared: runtime.AnyType}
+	case ast.VariantPattern:
+		var fields []*runtime.TypeSpec
+		if info := s.lookupEnum(p.EnumName); info != nil {
+			fields = info.Fields[p.Variant]
+		}
+		for i, field := range p.Fields {
+			fieldType := runtime.AnyType
+			if i < len(fields) {
+				fieldType = fields[i]
+			}
+			declarePattern(field, fieldType, s)
+		}
+	default:
+		for _, name := range patternBindings(pattern) {
+			s.vars[name] = &symbol{Type: runtime.AnyType, Declared: runtime.AnyType}
+		}
+	}
+}
+
+func (c *checker) check_prefix_expr(e ast.PrefixExpr, s *scope) *runtime.TypeSpec {
+	operand := c.check_expr(e.RightExpr, s)
+	c.line = e.Operator.Line
+	c.column = e.Operator.Column
+
+	switch e.Operator.Kind {
+	case lexer.TYPEOF:
+		return runtime.StringType
+	case lexer.DASH, lexer.TILDE:
+		if !c.mayBe(operand, runtime.NumberType) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Operator %s cannot be applied to %s", e.Operator.Value, operand)
+		}
+		return runtime.NumberType
+	}
+	return runtime.BooleanType
+}
+
+func (c *checker) check_binary_expr(e ast.BinaryExpr, s *scope) *runtime.TypeSpec {
+	lhs := c.check_expr(e.Left, s)
+	rhs := c.check_expr(e.Right, s)
+	c.line = e.Operator.Line
+	c.column = e.Operator.Column
+
+	return c.check_operator(e.Operator, lhs, rhs)
+}
+
+// check_operator returns the type of applying a binary operator, the rules
+// are those of the operator table of the runtime, see eval_binary_expr
+func (c *checker) check_operator(operator lexer.Token, lhs *runtime.TypeSpec, rhs *runtime.TypeSpec) *runtime.TypeSpec {
+	switch operator.Kind {
+	case lexer.PLUS:
+		return c.check_plus(operator, lhs, rhs)
+	case lexer.DASH, lexer.STAR, lexer.SLASH, lexer.PERCENT, lexer.DIV, lexer.STAR_STAR,
+		lexer.AMPERSAND, lexer.PIPE, lexer.CARET, lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT:
+		c.expect_numbers(operator, lhs, rhs)
+		return runtime.NumberType
+	case lexer.LESS, lexer.LESS_EQUALS, lexer.GREATER, lexer.GREATER_EQUALS:
+		bothNumbers := c.mayBe(lhs, runtime.NumberType) && c.mayBe(rhs, runtime.NumberType)
+		bothStrings := c.mayBe(lhs, runtime.StringType) && c.mayBe(rhs, runtime.StringType)
+		if !bothNumbers && !bothStrings {
+			c.errorf(operator.Line, operator.Column, "Operator %s cannot be applied to %s and %s", operator.Value, lhs, rhs)
+		}
+	}
+	return runtime.BooleanType
+}
+
+// check_plus accepts two numbers, two strings or two arrays of compatible
+// element types.
+func (c *checker) check_plus(operator lexer.Token, lhs *runtime.TypeSpec, rhs *runtime.TypeSpec) *runtime.TypeSpec {
+	if !known(lhs) || !known(rhs) {
+		for _, t := range []*runtime.TypeSpec{lhs, rhs} {
+			if t.Equal(runtime.NumberType) || t.Equal(runtime.StringType) {
+				return t
+			}
+		}
+		return runtime.AnyType
+	}
+
+	for _, t := range []*runtime.TypeSpec{runtime.NumberType, runtime.StringType} {
+		if c.assignable(lhs, t) && c.assignable(rhs, t) {
+			return t
+		}
+	}
+
+	if lhs.Kind == runtime.NamedKind && lhs.Name == runtime.ArrayType.Name && rhs.Kind == runtime.NamedKind && rhs.Name == runtime.ArrayType.Name {
+		switch {
+		case c.assignable(rhs, lhs):
+			return lhs
+		case c.assignable(lhs, rhs):
+			return rhs
+		}
+	} else if c.mayBe(lhs, runtime.NumberType) && c.mayBe(rhs, runtime.NumberType) || c.mayBe(lhs, runtime.StringType) && c.mayBe(rhs, runtime.StringType) {
+		// unions that may hold matching operands are only known at runtime
+		return runtime.AnyType
+	}
+
+	c.errorf(operator.Line, operator.Column, "Operator %s cannot be applied to %s and %s", operator.Value, lhs, rhs)
+	return runtime.AnyType
+}
+
+func (c *checker) expect_numbers(operator lexer.Token, lhs *runtime.TypeSpec, rhs *runtime.TypeSpec) {
+	if !c.mayBe(lhs, runtime.NumberType) || !c.mayBe(rhs, runtime.NumberType) {
+		c.errorf(operator.Line, operator.Column, "Operator %s cannot be applied to %s and %s", operator.Value, lhs, rhs)
+	}
+}
+
+func (c *checker) check_array_inst_expr(e ast.ArrayInstantiationExpr, s *scope) *runtime.TypeSpec {
+	elementType := s.resolve(e.Underlying)
+
+	for _, element := range e.Contents {
+		t := c.check_expr(element, s)
+		if !c.assignable(t, elementType) {
+			c.errorf(e.Line, e.Column, "Array of %s cannot contain %s", elementType, t)
+		}
+	}
+
+	return runtime.NamedType(runtime.ArrayType.Name, elementType)
+}
+
+func (c *checker) check_array_access_expr(e ast.ArrayAccessExpr, s *scope) *runtime.TypeSpec {
+	containerType := c.check_expr(e.Array, s)
+	indexType := c.check_expr(e.Index, s)
+
+	spec := containerType
+	if spec == nil || spec.Kind != runtime.NamedKind {
+		return runtime.AnyType
+	}
+
+	switch spec.Name {
+	case runtime.ArrayType.Name, runtime.StringType.Name:
+		if !c.mayBe(indexType, runtime.NumberType) {
+			c.errorf(0, 0, "Cannot index %s with %s", containerType, indexType)
+		}
+		if spec.Name == runtime.StringType.Name {
+			return containerType
+		}
+		if len(spec.Args) == 1 {
+			return spec.Args[0]
+		}
+	case runtime.MapType.Name:
+		if len(spec.Args) == 2 {
+			if !c.assignable(indexType, spec.Args[0]) {
+				c.errorf(0, 0, "Cannot index %s with %s", containerType, indexType)
+			}
+			return spec.Args[1]
+		}
+	case runtime.TupleType.Name:
+		if index, ok := e.Index.(ast.NumberExpr); ok && int(index.Value) >= 0 && int(index.Value) < len(spec.Args) {
+			return spec.Args[int(index.Value)]
+		}
+	}
+	return runtime.AnyType
+}
+
+func (c *checker) check_slice_expr(e ast.SliceExpr, s *scope) *runtime.TypeSpec {
+	containerType := c.check_expr(e.Array, s)
+
+	for _, bound := range []ast.Expr{e.Start, e.End, e.Step} {
+		if bound == nil {
+			continue
+		}
+		if boundType := c.check_expr(bound, s); !c.mayBe(boundType, runtime.NumberType) {
+			c.errorf(0, 0, "Slice bounds must be numbers, got %s", boundType)
+		}
+	}
+
+	if !known(containerType) || containerType.Kind != runtime.NamedKind {
+		return runtime.AnyType
+	}
+	if containerType.Name != runtime.ArrayType.Name && containerType.Name != runtime.StringType.Name {
+		c.errorf(0, 0, "Cannot slice %s", containerType)
+		return runtime.AnyType
+	}
+	return containerType
+}
+
+func (c *checker) check_struct_inst_expr(e ast.StructInstantiationExpr, s *scope) *runtime.TypeSpec {
+	info := s.lookupStruct(e.StructName)
+
+	for _, name := range e.Order {
+		propType := c.check_expr(e.Properties[name], s)
+		c.line = e.Line
+		c.column = e.Column
+		if info == nil {
+			continue
+		}
+
+		expected, exists := info.Props[name]
+		if !exists {
+			c.errorf(e.Line, e.Column, "Struct %s has no property %s", e.StructName, name)
+			continue
+		}
+
+		expected = runtime.SubstituteType(expected, typeParamsAsAny(info.TypeParams))
+		if !c.assignable(propType, expected) {
+			c.errorf(e.Line, e.Column, "Type mismatch for property %s in struct %s: expected %s got %s", name, e.StructName, expected, propType)
+		}
+	}
+
+	if info == nil {
+		return runtime.AnyType
+	}
+
+	for _, name := range info.Order {
+		if _, given := e.Properties[name]; given || info.Defaults[name] {
+			continue
+		}
+		if info.Required[name] {
+			c.errorf(e.Line, e.Column, "Missing required property %s in struct %s", name, e.StructName)
+		} else if c.strict {
+			expected := runtime.SubstituteType(info.Props[name], typeParamsAsAny(info.TypeParams))
+			if !runtime.IsAssignable(runtime.NullType, expected, true) {
+				c.errorf(e.Line, e.Column, "Missing property %s of non-nullable type %s in struct %s", name, expected, e.StructName)
+			}
+		}
+	}
+
+	return withTypeArgs(e.StructName, info.TypeParams)
+}
+
+func typeParamsAsAny(typeParams []string) map[string]*runtime.TypeSpec {
+	bindings := make(map[string]*runtime.TypeSpec)
+	for _, param := range typeParams {
+		bindings[param] = runtime.AnyType
+	}
+	return bindings
+}
+
+func (c *checker) check_member_access_expr(e ast.MemberAccessExpr, s *scope) *runtime.TypeSpec {
+	memberType, _ := c.resolve_member(e, s)
+	return memberType
+}
+
+// resolve_member returns the type of a member along with the struct that
+// declares it, which is an embedded one for promoted members.
+func (c *checker) resolve_member(e ast.MemberAccessExpr, s *scope) (*runtime.TypeSpec, *structInfo) {
+	objectType := c.check_expr(e.Struct, s)
+	c.line = e.Line
+	c.column = e.Column
+
+	info, bindings := s.structOf(objectType)
+	if info == nil {
+		return runtime.AnyType, nil
+	}
+
+	propType, exists := info.Props[e.Member]
+	if exists {
+		return runtime.SubstituteType(propType, bindings), info
+	}
+
+	owner, conflict := s.promoted(info, e.Member)
+	if conflict != "" {
+		c.errorf(e.Line, e.Column, "%s", conflict)
+		return runtime.AnyType, nil
+	}
+	if owner == nil {
+		c.errorf(e.Line, e.Column, "Member %s not found in struct %s", e.Member, info.Name)
+		return runtime.AnyType, nil
+	}
+	return runtime.SubstituteType(owner.Props[e.Member], typeParamsAsAny(owner.TypeParams)), owner
+}
+
+func (c *checker) check_call_expr(e ast.CallExpr, s *scope) *runtime.TypeSpec {
+	c.line = e.Line
+	c.column = e.Column
+
+	switch {
+	case e.Callee != nil:
+		calleeType := c.check_expr(e.Callee, s)
+		return c.check_call_args(signatureOf(calleeType), e, s)
+	case e.Struct != nil:
+		return c.check_method_call(e, s)
+	case s.isNative("freeze", e.FunctionName) && len(e.Arguments) == 1:
+		// freeze keeps the type of its argument
+		switch e.Arguments[0].(type) {
+		case ast.NamedArgumentExpr, ast.SpreadExpr:
+			return c.check_call_args(s.lookupCallable(e.FunctionName), e, s)
+		}
+		return c.check_expr(e.Arguments[0], s)
+	default:
+		return c.check_call_args(s.lookupCallable(e.FunctionName), e, s)
+	}
+}
+
+func (c *checker) check_method_call(e ast.CallExpr, s *scope) *runtime.TypeSpec {
+	objectType := c.check_expr(e.Struct, s)
+	c.line = e.Line
+	c.column = e.Column
+
+	info, bindings := s.structOf(objectType)
+	if info == nil {
+		if objectType != nil && objectType.Kind == runtime.NamedKind && (objectType.Name == runtime.MapType.Name || objectType.Name == runtime.SetType.Name) && mutatingMethods[e.FunctionName] {
+			c.check_mutable(e.Struct, s)
+		}
+		return c.check_call_args(nil, e, s)
+	}
+
+	if sig, exists := info.Methods[e.FunctionName]; exists {
+		return c.check_call_args(sig, e, s)
+	}
+
+	// properties holding functions are called like methods
+	if propType, exists := info.Props[e.FunctionName]; exists {
+		return c.check_call_args(signatureOf(runtime.SubstituteType(propType, bindings)), e, s)
+	}
+
+	owner, conflict := s.promoted(info, e.FunctionName)
+	if conflict != "" {
+		c.errorf(e.Line, e.Column, "%s", conflict)
+		return c.check_call_args(nil, e, s)
+	}
+
+	if owner != nil {
+		if propType, exists := owner.Props[e.FunctionName]; exists {
+			return c.check_call_args(signatureOf(runtime.SubstituteType(propType, typeParamsAsAny(owner.TypeParams))), e, s)
+		}
+
+		// the embedding struct may be passed as self, it is replaced by the
+		// embedded one
+		sig := *owner.Methods[e.FunctionName]
+		if len(sig.Params) > 0 {
+			sig.Params = append([]param(nil), sig.Params...)
+			sig.Params[0].Type = runtime.UnionType(sig.Params[0].Type, objectType)
+		}
+		return c.check_call_args(&sig, e, s)
+	}
+
+	c.errorf(e.Line, e.Column, "Method %s not found in struct %s", e.FunctionName, info.Name)
+	return c.check_call_args(nil, e, s)
+}
+
+// mutatingMethods are the builtin map and set methods that change their
+// receiver.
+var mutatingMethods = map[string]bool{"set": true, "delete": true, "add": true, "remove": true}
+
+// check_mutable reports changes made through a constant, whose value the
+// runtime freezes.
+func (c *checker) check_mutable(target ast.Expr, s *scope) {
+	name := runtime.GetRootVariableName(target)
+	if sym := s.lookupVar(name); sym != nil && sym.Constant {
+		c.errorf(0, 0, "Cannot modify constant %s", name)
+	}
+}
+
+// check_call_args matches the arguments of a call against sig the way the
+// runtime binds them: positional first, then named, then defaults.
+func (c *checker) check_call_args(sig *signature, e ast.CallExpr, s *scope) *runtime.TypeSpec {
+	var positional []*runtime.TypeSpec
+	named := make(map[string]*runtime.TypeSpec)
+	spread := false
+
+	for _, arg := range e.Arguments {
+		switch a := arg.(type) {
+		case ast.NamedArgumentExpr:
+			named[a.Name] = c.check_expr(a.Value, s)
+		case ast.SpreadExpr:
+			c.check_expr(a.Value, s)
+			spread = true
+		default:
+			positional = append(positional, c.check_expr(arg, s))
+		}
+	}
+	c.line = e.Line
+	c.column = e.Column
+
+	if sig == nil {
+		return runtime.AnyType
+	}
+
+	var fixed []param
+	var variadic *param
+	for i := range sig.Params {
+		if sig.Params[i].Variadic {
+			variadic = &sig.Params[i]
+		} else {
+			fixed = append(fixed, sig.Params[i])
+		}
+	}
+
+	if !spread && variadic == nil && len(positional) > len(fixed) {
+		c.errorf(e.Line, e.Column, "Function %s expects at most %d arguments but got %d", sig.Name, len(fixed), len(positional))
+	}
+
+	provided := make(map[string]bool)
+	for i, argType := range positional {
+		switch {
+		case i < len(fixed):
+			provided[fixed[i].Name] = true
+			c.check_argument(sig, fixed[i], argType, e.Line, e.Column)
+		case variadic != nil:
+			c.check_argument(sig, *variadic, argType, e.Line, e.Column)
+		}
+	}
+
+	for _, name := range sortedKeys(named) {
+		var target *param
+		for i := range fixed {
+			if fixed[i].Name == name {
+				target = &fixed[i]
+			}
+		}
+
+		switch {
+		case sig.Positional:
+			c.errorf(e.Line, e.Column, "Function %s does not accept named arguments", sig.Name)
+		case target == nil:
+			c.errorf(e.Line, e.Column, "Function %s has no parameter named %s", sig.Name, name)
+		case provided[name]:
+			c.errorf(e.Line, e.Column, "Argument %s of function %s was passed twice", name, sig.Name)
+		default:
+			provided[name] = true
+			c.check_argument(sig, *target, named[name], e.Line, e.Column)
+		}
+	}
+
+	if !spread {
+		for _, p := range fixed {
+			if !provided[p.Name] && !p.HasDefault {
+				c.errorf(e.Line, e.Column, "Missing argument %s in call to %s", p.Name, sig.Name)
+			}
+		}
+	}
+
+	return sig.Return
+}
+
+func (c *checker) check_argument(sig *signature, p param, argType *runtime.TypeSpec, line, column int) {
+	if !c.assignable(argType, p.Type) {
+		c.errorf(line, column, "Argument %s of function %s expected %s got %s", p.Name, sig.Name, p.Type, argType)
+	}
+}
+
+func (c *checker) check_assignment_expr(e ast.AssignmentExpr, s *scope) *runtime.TypeSpec {
+	valType := c.check_expr(e.Value, s)
+	c.line = e.Operator.Line
+	c.column = e.Operator.Column
+
+	if operator, ok := e.Operator.CompoundOperator(); ok {
+		valType = c.check_operator(operator, c.check_expr(e.Assigne, s), valType)
+	}
+
+	switch target := e.Assigne.(type) {
+	case ast.SymbolExpr:
+		if target.Value == "_" {
+			break
+		}
+
+		sym := s.lookupVar(target.Value)
+		if sym == nil {
+			break
+		}
+		if sym.Constant {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign to constant %s", target.Value)
+		}
+		if !c.assignable(valType, sym.Declared) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign %s to %s of type %s", valType, target.Value, sym.Declared)
+		}
+
+		// untyped variables may change type, only keep what stays the same
+		if known(sym.Declared) {
+			sym.Type = sym.Declared
+		} else if sym.Type != valType {
+			sym.Type = runtime.AnyType
+		}
+	case ast.MemberAccessExpr:
+		memberType, owner := c.resolve_member(target, s)
+		c.check_mutable(target, s)
+		if owner != nil && owner.Readonly[target.Member] {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign to readonly property %s of struct %s", target.Member, owner.Name)
+		}
+		if known(memberType) && !c.assignable(valType, memberType) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign %s to member %s of type %s", valType, target.Member, memberType)
+		}
+	case ast.ArrayAccessExpr:
+		elementType := c.check_array_access_expr(target, s)
+		c.check_mutable(target, s)
+		if known(elementType) && !c.assignable(valType, elementType) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign %s to an element of type %s", valType, elementType)
+		}
+	case ast.SliceExpr:
+		sliceType := c.check_slice_expr(target, s)
+		c.check_mutable(target, s)
+		if sliceType.Equal(runtime.StringType) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign to a slice of string")
+		} else if known(sliceType) && !c.assignable(valType, sliceType) {
+			c.errorf(e.Operator.Line, e.Operator.Column, "Cannot assign %s to a slice of %s", valType, sliceType)
+		}
+	case ast.TupleExpr:
+		for _, element := range target.Elements {
+			c.check_expr(element, s)
+		}
+	}
+
+	return valType
+}
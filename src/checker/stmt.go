@@ -0,0 +1,577 @@
+package checker
+
+import (
+	"fmt"
+	"shiplang/src/ast"
+	"shiplang/src/lexer"
+	"shiplang/src/runtime"
+)
+
+// check_block declares the types, structs and functions of a block before
+// checking its statements, so bodies may refer to declarations further down.
+func (c *checker) check_block(body []ast.Stmt, s *scope) {
+	c.hoist(body, s)
+
+	for _, stmt := range body {
+		c.check_stmt(stmt, s)
+	}
+}
+
+func (c *checker) hoist(body []ast.Stmt, s *scope) {
+	for _, stmt := range body {
+		if decl, ok := stmt.(ast.TypeDeclStmt); ok {
+			c.declare_type(decl, s)
+		}
+	}
+
+	for _, stmt := range body {
+		if decl, ok := stmt.(ast.StructDeclStmt); ok {
+			info := &structInfo{
+				Name:       decl.StructName,
+				TypeParams: decl.TypeParams,
+				Props:      make(map[string]*runtime.TypeSpec),
+				Order:      decl.Order,
+				Defaults:   make(map[string]bool),
+				Required:   make(map[string]bool),
+				Readonly:   make(map[string]bool),
+				Embedded:   decl.Embedded,
+				Methods:    make(map[string]*signature),
+			}
+			// type parameters stay as they are, shadowing aliases of the
+			// same name
+			typeScope := newScope(s)
+			for _, typeParam := range decl.TypeParams {
+				typeScope.aliases[typeParam] = runtime.NamedType(typeParam)
+			}
+			for _, name := range decl.Order {
+				prop := decl.Properties[name]
+				info.Props[name] = typeScope.resolve(prop.Type)
+				info.Defaults[name] = prop.Default != nil
+				info.Required[name] = prop.Required
+				info.Readonly[name] = prop.Readonly
+			}
+			s.structs[decl.StructName] = info
+		}
+	}
+
+	for _, stmt := range body {
+		switch decl := stmt.(type) {
+		case ast.EnumDeclStmt:
+			s.vars[decl.EnumName] = &symbol{Type: runtime.EnumType, Declared: runtime.EnumType, Constant: true}
+			info := &enumInfo{Name: decl.EnumName, Fields: make(map[string][]*runtime.TypeSpec)}
+			for _, variant := range decl.Variants {
+				info.Variants = append(info.Variants, variant.Name)
+				for _, field := range variant.Fields {
+					info.Fields[variant.Name] = append(info.Fields[variant.Name], s.resolve(field.Type))
+				}
+			}
+			s.enums[decl.EnumName] = info
+		case ast.FnDeclStmt:
+			sig := c.fn_signature(decl, nil, s)
+			s.fns[decl.FnName] = sig
+			s.vars[decl.FnName] = &symbol{Type: fnType(sig), Declared: fnType(sig), Constant: true}
+		case ast.ImplStmt:
+			info := s.lookupStruct(decl.Struct)
+			if info == nil {
+				c.errorf(0, 0, "Struct %s not defined", decl.Struct)
+				continue
+			}
+			info.Methods[decl.Method.FnName] = c.fn_signature(decl.Method, info.TypeParams, s)
+		}
+	}
+}
+
+func (c *checker) check_stmt(stmt ast.Stmt, s *scope) {
+	switch n := stmt.(type) {
+	case ast.BlockStmt:
+		c.check_block(n.Body, newScope(s))
+	case ast.ExpressionStmt:
+		c.check_expr(n.Expression, s)
+	case ast.VarDeclStmt:
+		c.check_var_decl_stmt(n, s)
+	case ast.FnDeclStmt:
+		c.check_fn_body(n, nil, s.fns[n.FnName], s)
+	case ast.StructDeclStmt:
+		c.check_struct_decl_stmt(n, s)
+	case ast.ImplStmt:
+		if info := s.lookupStruct(n.Struct); info != nil {
+			c.check_fn_body(n.Method, info.TypeParams, info.Methods[n.Method.FnName], s)
+		}
+	case ast.ReturnStmt:
+		c.check_return_stmt(n, s)
+	case ast.IfStmt:
+		c.check_if_stmt(n, s)
+	case ast.SwitchStmt:
+		c.check_switch_stmt(n, s)
+	case ast.WhileStmt:
+		c.check_expr(n.Condition, s)
+		c.check_block(n.Body.Body, newScope(s))
+	case ast.DoWhileStmt:
+		c.check_block(n.Body.Body, newScope(s))
+		c.check_expr(n.Condition, s)
+	case ast.LoopStmt:
+		c.check_block(n.Body.Body, newScope(s))
+	case ast.ForStmt:
+		loopScope := newScope(s)
+		c.check_stmt(n.Init, loopScope)
+		if n.Cond != nil {
+			c.check_expr(n.Cond, loopScope)
+		}
+		c.check_stmt(n.Post, loopScope)
+		c.check_block(n.Body.Body, newScope(loopScope))
+	case ast.ForeachStmt:
+		c.check_foreach_stmt(n, s)
+	case ast.ImportStmt:
+		// imported declarations are only known once the module runs
+		for _, name := range n.Modules {
+			s.vars[name] = &symbol{Type: runtime.AnyType, Declared: runtime.AnyType, Constant: true}
+		}
+	}
+}
+
+func (c *checker) check_struct_decl_stmt(decl ast.StructDeclStmt, s *scope) {
+	info := s.lookupStruct(decl.StructName)
+
+	owners := make(map[string]string)
+	for _, embedded := range decl.Embedded {
+		embeddedInfo := s.lookupStruct(embedded)
+		if embeddedInfo == nil {
+			c.errorf(decl.Line, decl.Column, "Cannot embed %s in struct %s, it is not a struct", embedded, decl.StructName)
+			continue
+		}
+
+		for _, name := range embeddedInfo.Order {
+			if _, shadowed := info.Props[name]; shadowed {
+				continue
+			}
+			if owner, exists := owners[name]; exists {
+				c.errorf(decl.Line, decl.Column, "Struct %s embeds both %s and %s which define %s", decl.StructName, owner, embedded, name)
+			}
+			owners[name] = embedded
+		}
+	}
+	for _, name := range decl.Order {
+		prop := decl.Properties[name]
+		if prop.Default == nil {
+			continue
+		}
+
+		defaultType := c.check_expr(prop.Default, s)
+		expected := runtime.SubstituteType(info.Props[name], typeParamsAsAny(info.TypeParams))
+		if !c.assignable(defaultType, expected) {
+			c.errorf(decl.Line, decl.Column, "Default value of property %s in struct %s expected %s got %s", name, decl.StructName, expected, defaultType)
+		}
+	}
+}
+
+func (c *checker) declare_type(decl ast.TypeDeclStmt, s *scope) {
+	underlying := s.resolve(decl.Underlying)
+
+	if !decl.Distinct {
+		s.aliases[decl.TypeName] = underlying
+		return
+	}
+
+	// distinct types are created through a conversion function
+	sig := &signature{
+		Name:   decl.TypeName,
+		Params: []param{{Name: "value", Type: underlying}},
+		Return: runtime.NamedType(decl.TypeName),
+	}
+	s.fns[decl.TypeName] = sig
+	s.vars[decl.TypeName] = &symbol{Type: fnType(sig), Declared: fnType(sig), Constant: true}
+}
+
+// fn_signature resolves the parameter and return types of a function. Type
+// parameters are treated as any, both for callers and inside the body.
+func (c *checker) fn_signature(decl ast.FnDeclStmt, outerTypeParams []string, s *scope) *signature {
+	typeScope := newScope(s)
+	for _, typeParam := range append(append([]string{}, outerTypeParams...), decl.TypeParams...) {
+		typeScope.aliases[typeParam] = runtime.AnyType
+	}
+
+	sig := &signature{Name: decl.FnName, Return: runtime.AnyType}
+	for _, p := range decl.Parameters {
+		paramType := typeScope.resolve(p.Type)
+		if p.IsVariadic {
+			if arrayType, ok := p.Type.(ast.ArrayType); ok {
+				paramType = typeScope.resolve(arrayType.Underlying)
+			}
+		}
+		sig.Params = append(sig.Params, param{
+			Name:       p.Name,
+			Type:       paramType,
+			HasDefault: p.Default != nil,
+			Variadic:   p.IsVariadic,
+		})
+	}
+
+	if decl.ReturnType != nil {
+		sig.Return = typeScope.resolve(decl.ReturnType)
+	}
+	return sig
+}
+
+func fnType(sig *signature) *runtime.TypeSpec {
+	params := make([]*runtime.TypeSpec, len(sig.Params))
+	for i, p := range sig.Params {
+		params[i] = p.Type
+		if p.Variadic {
+			variadic := *p.Type
+			variadic.Variadic = true
+			params[i] = &variadic
+		}
+	}
+	return runtime.FnType(params, sig.Return)
+}
+
+func (c *checker) check_fn_body(decl ast.FnDeclStmt, outerTypeParams []string, sig *signature, s *scope) {
+	if sig == nil {
+		return
+	}
+
+	fnScope := newScope(s)
+	fnScope.fn = sig
+	for _, typeParam := range append(append([]string{}, outerTypeParams...), decl.TypeParams...) {
+		fnScope.aliases[typeParam] = runtime.AnyType
+	}
+
+	for i, p := range decl.Parameters {
+		paramType := sig.Params[i].Type
+		if p.IsVariadic {
+			paramType = runtime.NamedType(runtime.ArrayType.Name, paramType)
+		}
+
+		if p.Default != nil {
+			if defaultType := c.check_expr(p.Default, fnScope); !c.assignable(defaultType, paramType) {
+				c.errorf(0, 0, "Default value of parameter %s of function %s expected %s got %s", p.Name, decl.FnName, paramType, defaultType)
+			}
+		}
+		fnScope.vars[p.Name] = &symbol{Type: paramType, Declared: paramType}
+	}
+
+	c.check_block(decl.Body.Body, fnScope)
+}
+
+func (c *checker) check_var_decl_stmt(decl ast.VarDeclStmt, s *scope) {
+	c.line = decl.Line
+	c.column = decl.Column
+
+	valType := runtime.NullType
+	if decl.AssignedValue != nil {
+		valType = c.check_expr(decl.AssignedValue, s)
+	}
+	c.line = decl.Line
+	c.column = decl.Column
+
+	declared := runtime.AnyType
+	if decl.ExplicitType != nil {
+		declared = s.resolve(decl.ExplicitType)
+	} else if decl.AssignedValue != nil {
+		declared = runtime.InferType(decl.AssignedValue, valType, valType)
+	}
+
+	if decl.Pattern != nil {
+		for _, name := range patternBindings(decl.Pattern) {
+			s.vars[name] = &symbol{Type: runtime.AnyType, Declared: runtime.AnyType, Constant: decl.IsConstant}
+		}
+		return
+	}
+
+	if _, exists := s.vars[decl.VarName]; exists {
+		c.errorf(decl.Line, decl.Column, "Variable %s is already declared in this scope", decl.VarName)
+	}
+
+	if !c.assignable(valType, declared) {
+		if valType.Equal(runtime.NullType) {
+			c.errorf(decl.Line, decl.Column, "Cannot declare %s of non-nullable type %s as null", decl.VarName, declared)
+		} else {
+			c.errorf(decl.Line, decl.Column, "Cannot declare %s of type %s with a value of type %s", decl.VarName, declared, valType)
+		}
+	}
+
+	known := valType
+	if decl.ExplicitType != nil {
+		known = declared
+	}
+	s.vars[decl.VarName] = &symbol{Type: known, Declared: declared, Constant: decl.IsConstant}
+}
+
+func patternBindings(pattern ast.Pattern) []string {
+	switch p := pattern.(type) {
+	case ast.BindingPattern:
+		return []string{p.Name}
+	case ast.TuplePattern:
+		return collectBindings(p.Elements)
+	case ast.ArrayPattern:
+		names := collectBindings(p.Elements)
+		if p.HasRest && p.Rest != "" {
+			names = append(names, p.Rest)
+		}
+		return names
+	case ast.VariantPattern:
+		return collectBindings(p.Fields)
+	case ast.StructPattern:
+		var names []string
+		for _, field := range p.Fields {
+			if field.Pattern == nil {
+				names = append(names, field.Name)
+			} else {
+				names = append(names, patternBindings(field.Pattern)...)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func collectBindings(patterns []ast.Pattern) []string {
+	var names []string
+	for _, pattern := range patterns {
+		names = append(names, patternBindings(pattern)...)
+	}
+	return names
+}
+
+func (c *checker) check_return_stmt(r ast.ReturnStmt, s *scope) {
+	valType := runtime.NullType
+	if r.Value != nil {
+		valType = c.check_expr(r.Value, s)
+	}
+
+	fn := s.enclosingFn()
+	if fn == nil || !known(fn.Return) {
+		return
+	}
+
+	if !c.assignable(valType, fn.Return) {
+		c.errorf(r.Line, r.Column, "Function %s must return %s but returns %s", fn.Name, fn.Return, valType)
+	}
+}
+
+func (c *checker) check_if_stmt(n ast.IfStmt, s *scope) {
+	c.check_expr(n.Condition, s)
+
+	ifScope, elseScope := newScope(s), newScope(s)
+	if name, tested, equal, ok := typeofTest(n.Condition); ok {
+		if sym := s.lookupVar(name); sym != nil {
+			matching, rest := narrow(sym.Type, tested)
+			if !equal {
+				matching, rest = rest, matching
+			}
+			ifScope.vars[name] = &symbol{Type: matching, Declared: sym.Declared, Constant: sym.Constant}
+			elseScope.vars[name] = &symbol{Type: rest, Declared: sym.Declared, Constant: sym.Constant}
+
+			// after if (typeof x == "string") { return ...; } x is known
+			// not to be a string for the rest of the block
+			if len(n.ElifBodies) == 0 && len(n.ElseBody.Body) == 0 && endsWithReturn(n.IfBody) {
+				defer func() {
+					narrowed := *sym
+					narrowed.Type = rest
+					s.vars[name] = &narrowed
+				}()
+			}
+		}
+	}
+
+	c.check_block(n.IfBody.Body, ifScope)
+
+	for _, elif := range n.ElifBodies {
+		c.check_expr(elif.Condition, elseScope)
+		c.check_block(elif.Body.Body, newScope(elseScope))
+	}
+
+	c.check_block(n.ElseBody.Body, elseScope)
+}
+
+func (c *checker) check_switch_stmt(n ast.SwitchStmt, s *scope) {
+	subject := c.check_expr(n.Subject, s)
+	seen := make(map[string]bool)
+
+	for _, sc := range n.Cases {
+		for _, label := range sc.Labels {
+			valueType := c.check_expr(label.Value, s)
+			c.line = n.Line
+			c.column = n.Column
+
+			if label.Upper != nil {
+				upperType := c.check_expr(label.Upper, s)
+				c.line = n.Line
+				c.column = n.Column
+
+				var bound *runtime.TypeSpec
+				for _, t := range []*runtime.TypeSpec{runtime.NumberType, runtime.StringType} {
+					if c.mayBe(valueType, t) && c.mayBe(upperType, t) {
+						bound = t
+						break
+					}
+				}
+				if bound == nil {
+					c.errorf(n.Line, n.Column, "Case range must be bounded by two numbers or two strings, got %s and %s", valueType, upperType)
+				} else if !c.mayBe(subject, bound) {
+					c.errorf(n.Line, n.Column, "Case range of %s can never match %s", bound, subject)
+				}
+				continue
+			}
+
+			if !c.mayBe(subject, valueType) && !c.mayBe(valueType, subject) {
+				c.errorf(n.Line, n.Column, "Case of type %s can never match %s", valueType, subject)
+			}
+
+			var key string
+			switch v := label.Value.(type) {
+			case ast.NumberExpr:
+				key = fmt.Sprintf("%g", v.Value)
+			case ast.StringExpr:
+				key = fmt.Sprintf("%q", v.Value)
+			default:
+				continue
+			}
+			if seen[key] {
+				c.errorf(n.Line, n.Column, "Duplicate case %s in switch", key)
+			}
+			seen[key] = true
+		}
+
+		c.check_block(sc.Body.Body, newScope(s))
+	}
+
+	c.check_block(n.Default.Body, newScope(s))
+}
+
+func endsWithReturn(block ast.BlockStmt) bool {
+	if len(block.Body) == 0 {
+		return false
+	}
+	_, isReturn := block.Body[len(block.Body)-1].(ast.ReturnStmt)
+	return isReturn
+}
+
+// typeofTest recognises conditions such as typeof x == "string".
+func typeofTest(condition ast.Expr) (string, *runtime.TypeSpec, bool, bool) {
+	binary, ok := condition.(ast.BinaryExpr)
+	if !ok || (binary.Operator.Kind != lexer.EQUALS && binary.Operator.Kind != lexer.NOT_EQUALS) {
+		return "", nil, false, false
+	}
+
+	prefix, literal := binary.Left, binary.Right
+	if _, isString := prefix.(ast.StringExpr); isString {
+		prefix, literal = literal, prefix
+	}
+
+	typeofExpr, ok := prefix.(ast.PrefixExpr)
+	if !ok || typeofExpr.Operator.Kind != lexer.TYPEOF {
+		return "", nil, false, false
+	}
+	symbol, ok := typeofExpr.RightExpr.(ast.SymbolExpr)
+	if !ok {
+		return "", nil, false, false
+	}
+	typeName, ok := literal.(ast.StringExpr)
+	if !ok {
+		return "", nil, false, false
+	}
+
+	return symbol.Value, runtime.ParseType(typeName.Value), binary.Operator.Kind == lexer.EQUALS, true
+}
+
+// narrow splits t into the part matching tested and the rest. Only unions
+// can be narrowed in the other branch, everything else stays as it was.
+func narrow(t *runtime.TypeSpec, tested *runtime.TypeSpec) (*runtime.TypeSpec, *runtime.TypeSpec) {
+	spec := t
+	if spec == nil {
+		return tested, t
+	}
+	if spec.Kind == runtime.NullableKind {
+		spec = runtime.UnionType(spec.Elem, runtime.NullType)
+	}
+	if spec.Kind != runtime.UnionKind {
+		return tested, t
+	}
+
+	var rest []*runtime.TypeSpec
+	for _, member := range spec.Args {
+		if !member.Equal(tested) {
+			rest = append(rest, member)
+		}
+	}
+	if len(rest) == 0 || len(rest) == len(spec.Args) {
+		return tested, t
+	}
+	return tested, runtime.UnionType(rest...)
+}
+
+func (c *checker) check_foreach_stmt(n ast.ForeachStmt, s *scope) {
+	collectionType := c.check_expr(n.Collection, s)
+	keyType, valueType := runtime.AnyType, runtime.AnyType
+
+	spec := collectionType
+	if spec != nil && spec.Kind == runtime.NamedKind {
+		switch {
+		case spec.Name == runtime.ArrayType.Name && len(spec.Args) == 1,
+			spec.Name == runtime.SetType.Name && len(spec.Args) == 1:
+			keyType = runtime.NumberType
+			valueType = spec.Args[0]
+		case spec.Name == runtime.StringType.Name:
+			keyType = runtime.NumberType
+			valueType = runtime.StringType
+		case spec.Name == runtime.MapType.Name && len(spec.Args) == 2:
+			keyType = spec.Args[0]
+			valueType = spec.Args[1]
+			if n.KeyIterator == "" {
+				valueType = keyType
+			}
+		case spec.Name == runtime.NumberType.Name, spec.Name == runtime.BooleanType.Name:
+			c.errorf(n.Line, n.Column, "Cannot iterate over %s", collectionType)
+		default:
+			if info, _ := s.structOf(collectionType); info != nil {
+				keyType = runtime.StringType
+				valueType = runtime.StringType
+				if n.KeyIterator != "" {
+					valueType = commonPropType(info)
+				}
+			}
+		}
+	}
+
+	loopScope := newScope(s)
+	loopScope.vars[n.Iterator] = c.foreach_var(n.Iterator, n.IteratorType, valueType, n.Line, n.Column, s)
+	if n.KeyIterator != "" {
+		loopScope.vars[n.KeyIterator] = c.foreach_var(n.KeyIterator, n.KeyType, keyType, n.Line, n.Column, s)
+	}
+	c.check_block(n.Body.Body, loopScope)
+}
+
+// foreach_var declares a loop variable, an annotated type must accept the
+// elements
+func (c *checker) foreach_var(name string, annotation ast.Type, elementType *runtime.TypeSpec, line, column int, s *scope) *symbol {
+	if annotation == nil {
+		return &symbol{Type: elementType, Declared: runtime.AnyType}
+	}
+
+	declared := s.resolve(annotation)
+	if known(elementType) && !c.assignable(elementType, declared) {
+		c.errorf(line, column, "Foreach variable %s expected %s got %s", name, declared, elementType)
+	}
+	return &symbol{Type: declared, Declared: declared}
+}
+
+// commonPropType is the type shared by every property of a struct, or any
+func commonPropType(info *structInfo) *runtime.TypeSpec {
+	if len(info.TypeParams) > 0 {
+		return runtime.AnyType
+	}
+
+	var common *runtime.TypeSpec
+	for _, name := range info.Order {
+		if common == nil {
+			common = info.Props[name]
+		} else if !info.Props[name].Equal(common) {
+			return runtime.AnyType
+		}
+	}
+	if common == nil {
+		return runtime.AnyType
+	}
+	return common
+}
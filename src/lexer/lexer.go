@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 type regexHandler func(lex *lexer, regex *regexp.Regexp)
@@ -17,13 +18,18 @@ type lexer struct {
 	Tokens   []Token
 	source   string
 	pos      int
+	line     int
+	start    int // offset of the token being matched
 }
 
 func (lex *lexer) advanceN(n int) {
+	lex.line += strings.Count(lex.source[lex.pos:lex.pos+n], "\n")
 	lex.pos += n
 }
 
 func (lex *lexer) push(token Token) {
+	token.Line = lex.line
+	token.Column = lex.start - strings.LastIndex(lex.source[:lex.start], "\n")
 	lex.Tokens = append(lex.Tokens, token)
 }
 
@@ -48,6 +54,7 @@ func Tokenize(source string) []Token {
 	for !lex.at_eof() {
 		matched := false
 
+		lex.start = lex.pos
 		for _, pattern := range lex.patterns {
 			loc := pattern.regex.FindStringIndex(lex.remainder())
 
@@ -64,6 +71,7 @@ func Tokenize(source string) []Token {
 
 	}
 
+	lex.start = lex.pos
 	lex.push(NewToken(EOF, "EOF"))
 	return lex.Tokens
 }
@@ -71,6 +79,7 @@ func Tokenize(source string) []Token {
 func createLexer(source string) *lexer {
 	return &lexer{
 		pos:    0,
+		line:   1,
 		source: source,
 		Tokens: make([]Token, 0),
 		patterns: []regexPattern{
@@ -89,14 +98,22 @@ func createLexer(source string) *lexer {
 			{regexp.MustCompile(`\)`), defaultHandler(CLOSE_PAREN, ")")},
 			{regexp.MustCompile(`==`), defaultHandler(EQUALS, "==")},
 			{regexp.MustCompile(`!=`), defaultHandler(NOT_EQUALS, "!=")},
+			{regexp.MustCompile(`=>`), defaultHandler(FAT_ARROW, "=>")},
 			{regexp.MustCompile(`=`), defaultHandler(ASSIGNMENT, "=")},
 			{regexp.MustCompile(`!`), defaultHandler(NOT, "!")},
+			{regexp.MustCompile(`<<`), defaultHandler(SHIFT_LEFT, "<<")},
 			{regexp.MustCompile(`<=`), defaultHandler(LESS_EQUALS, "<=")},
 			{regexp.MustCompile(`<`), defaultHandler(LESS, "<")},
+			{regexp.MustCompile(`>>`), defaultHandler(SHIFT_RIGHT, ">>")},
 			{regexp.MustCompile(`>=`), defaultHandler(GREATER_EQUALS, ">=")},
 			{regexp.MustCompile(`>`), defaultHandler(GREATER, ">")},
 			{regexp.MustCompile(`\|\|`), defaultHandler(OR, "||")},
 			{regexp.MustCompile(`&&`), defaultHandler(AND, "&&")},
+			{regexp.MustCompile(`\|`), defaultHandler(PIPE, "|")},
+			{regexp.MustCompile(`&`), defaultHandler(AMPERSAND, "&")},
+			{regexp.MustCompile(`\^`), defaultHandler(CARET, "^")},
+			{regexp.MustCompile(`~`), defaultHandler(TILDE, "~")},
+			{regexp.MustCompile(`\.\.\.`), defaultHandler(DOT_DOT_DOT, "...")},
 			{regexp.MustCompile(`\.\.`), defaultHandler(DOT_DOT, "..")},
 			{regexp.MustCompile(`\.`), defaultHandler(DOT, ".")},
 			{regexp.MustCompile(`;`), defaultHandler(SEMI_COLON, ";")},
@@ -108,6 +125,10 @@ func createLexer(source string) *lexer {
 			{regexp.MustCompile(`--`), defaultHandler(MINUS_MINUS, "--")},
 			{regexp.MustCompile(`\+=`), defaultHandler(PLUS_EQUALS, "+=")},
 			{regexp.MustCompile(`-=`), defaultHandler(MINUS_EQUALS, "-=")},
+			{regexp.MustCompile(`\*=`), defaultHandler(STAR_EQUALS, "*=")},
+			{regexp.MustCompile(`/=`), defaultHandler(SLASH_EQUALS, "/=")},
+			{regexp.MustCompile(`%=`), defaultHandler(PERCENT_EQUALS, "%=")},
+			{regexp.MustCompile(`\*\*`), defaultHandler(STAR_STAR, "**")},
 			{regexp.MustCompile(`\+`), defaultHandler(PLUS, "+")},
 			{regexp.MustCompile(`-`), defaultHandler(DASH, "-")},
 			{regexp.MustCompile(`/`), defaultHandler(SLASH, "/")},
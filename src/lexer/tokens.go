@@ -36,20 +36,26 @@ const (
 	// Logical
 	OR
 	AND
+	PIPE
 
 	// Symbols
 	DOT
 	DOT_DOT
+	DOT_DOT_DOT
 	SEMI_COLON
 	COLON
 	QUESTION
 	COMMA
+	FAT_ARROW
 
 	// Shorthand
 	PLUS_PLUS
 	MINUS_MINUS
 	PLUS_EQUALS
 	MINUS_EQUALS
+	STAR_EQUALS
+	SLASH_EQUALS
+	PERCENT_EQUALS
 	NULLISH_ASSIGNMENT // ??=
 
 	//Maths
@@ -58,6 +64,15 @@ const (
 	SLASH
 	STAR
 	PERCENT
+	STAR_STAR
+	DIV
+
+	// Bitwise, | is PIPE
+	AMPERSAND
+	CARET
+	TILDE
+	SHIFT_LEFT
+	SHIFT_RIGHT
 
 	// Reserved Keywords
 	LET
@@ -83,6 +98,17 @@ const (
 	RETURN
 	BREAK
 
+	ENUM
+	MATCH
+	MAP
+	SET
+	TYPE
+	SWITCH
+	CASE
+	DEFAULT
+	DO
+	LOOP
+
 	// Misc
 	NUM_TOKENS
 )
@@ -108,11 +134,24 @@ var reserved_words map[string]TokenKind = map[string]TokenKind{
 	"static":  STATIC,
 	"return":  RETURN,
 	"break":   BREAK,
+	"enum":    ENUM,
+	"match":   MATCH,
+	"map":     MAP,
+	"set":     SET,
+	"type":    TYPE,
+	"div":     DIV,
+	"switch":  SWITCH,
+	"case":    CASE,
+	"default": DEFAULT,
+	"do":      DO,
+	"loop":    LOOP,
 }
 
 type Token struct {
-	Kind  TokenKind
-	Value string
+	Kind   TokenKind
+	Value  string
+	Line   int
+	Column int
 }
 
 func (token Token) isOneOfMany(expectedTokens ...TokenKind) bool {
@@ -125,6 +164,30 @@ func (token Token) isOneOfMany(expectedTokens ...TokenKind) bool {
 	return false
 }
 
+// IsKeyword reports whether the token was produced from a reserved word.
+func (token Token) IsKeyword() bool {
+	kind, exists := reserved_words[token.Value]
+	return exists && kind == token.Kind
+}
+
+// compoundOperators maps each compound assignment to the binary operator it
+// applies before assigning
+var compoundOperators = map[TokenKind]Token{
+	PLUS_EQUALS:    NewToken(PLUS, "+"),
+	MINUS_EQUALS:   NewToken(DASH, "-"),
+	STAR_EQUALS:    NewToken(STAR, "*"),
+	SLASH_EQUALS:   NewToken(SLASH, "/"),
+	PERCENT_EQUALS: NewToken(PERCENT, "%"),
+}
+
+// CompoundOperator returns the binary operator applied by a compound
+// assignment such as +=, ok is false for plain assignments.
+func (token Token) CompoundOperator() (operator Token, ok bool) {
+	operator, ok = compoundOperators[token.Kind]
+	operator.Line = token.Line
+	return operator, ok
+}
+
 func (token Token) Debug() {
 	if token.isOneOfMany(IDENTIFIER, NUMBER, STRING) {
 		fmt.Printf("%s(%s)\n", TokenKindString(token.Kind), token.Value)
@@ -135,7 +198,8 @@ func (token Token) Debug() {
 
 func NewToken(kind TokenKind, value string) Token {
 	return Token{
-		kind, value,
+		Kind:  kind,
+		Value: value,
 	}
 }
 
@@ -187,10 +251,14 @@ func TokenKindString(kind TokenKind) string {
 		return "or"
 	case AND:
 		return "and"
+	case PIPE:
+		return "pipe"
 	case DOT:
 		return "dot"
 	case DOT_DOT:
 		return "dot_dot"
+	case DOT_DOT_DOT:
+		return "dot_dot_dot"
 	case SEMI_COLON:
 		return "semi_colon"
 	case COLON:
@@ -199,6 +267,8 @@ func TokenKindString(kind TokenKind) string {
 		return "question"
 	case COMMA:
 		return "comma"
+	case FAT_ARROW:
+		return "fat_arrow"
 	case PLUS_PLUS:
 		return "plus_plus"
 	case MINUS_MINUS:
@@ -207,6 +277,12 @@ func TokenKindString(kind TokenKind) string {
 		return "plus_equals"
 	case MINUS_EQUALS:
 		return "minus_equals"
+	case STAR_EQUALS:
+		return "star_equals"
+	case SLASH_EQUALS:
+		return "slash_equals"
+	case PERCENT_EQUALS:
+		return "percent_equals"
 	case NULLISH_ASSIGNMENT:
 		return "nullish_assignment"
 	case PLUS:
@@ -219,6 +295,20 @@ func TokenKindString(kind TokenKind) string {
 		return "star"
 	case PERCENT:
 		return "percent"
+	case STAR_STAR:
+		return "star_star"
+	case DIV:
+		return "div"
+	case AMPERSAND:
+		return "ampersand"
+	case CARET:
+		return "caret"
+	case TILDE:
+		return "tilde"
+	case SHIFT_LEFT:
+		return "shift_left"
+	case SHIFT_RIGHT:
+		return "shift_right"
 	case LET:
 		return "let"
 	case CONST:
@@ -247,12 +337,34 @@ func TokenKindString(kind TokenKind) string {
 		return "export"
 	case IN:
 		return "in"
+	case TYPEOF:
+		return "typeof"
 	case STATIC:
 		return "static"
 	case STRUCT:
 		return "struct"
 	case RETURN:
 		return "return"
+	case ENUM:
+		return "enum"
+	case MATCH:
+		return "match"
+	case MAP:
+		return "map"
+	case SET:
+		return "set"
+	case TYPE:
+		return "type"
+	case SWITCH:
+		return "switch"
+	case CASE:
+		return "case"
+	case DEFAULT:
+		return "default"
+	case DO:
+		return "do"
+	case LOOP:
+		return "loop"
 	default:
 		return fmt.Sprintf("unknown(%d)", kind)
 	}
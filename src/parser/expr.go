@@ -63,19 +63,38 @@ func parse_binary_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	}
 }
 
+// parse_exponent_expr parses ** which is right associative and binds tighter
+// than prefix operators: 2 ** 3 ** 2 is 2 ** 9 and -2 ** 2 is -4
+func parse_exponent_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
+	optk := p.advance()
+	right := parse_expr(p, bp-1)
+
+	return ast.BinaryExpr{
+		Left:     left,
+		Operator: optk,
+		Right:    right,
+	}
+}
+
 func parse_assignment_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	operatorToken := p.advance()
 
 	switch operatorToken.Kind {
 	case lexer.PLUS_PLUS:
+		operator := lexer.NewToken(lexer.PLUS_EQUALS, "+=")
+		operator.Line = operatorToken.Line
+		operator.Column = operatorToken.Column
 		return ast.AssignmentExpr{
-			Operator: lexer.NewToken(lexer.PLUS_EQUALS, "+="),
+			Operator: operator,
 			Value:    ast.NumberExpr{Value: 1},
 			Assigne:  left,
 		}
 	case lexer.MINUS_MINUS:
+		operator := lexer.NewToken(lexer.MINUS_EQUALS, "-=")
+		operator.Line = operatorToken.Line
+		operator.Column = operatorToken.Column
 		return ast.AssignmentExpr{
-			Operator: lexer.NewToken(lexer.MINUS_EQUALS, "-="),
+			Operator: operator,
 			Value:    ast.NumberExpr{Value: 1},
 			Assigne:  left,
 		}
@@ -92,7 +111,18 @@ func parse_assignment_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr
 
 func parse_prefix_expr(p *parser) ast.Expr {
 	operatorToken := p.advance()
-	rhs := parse_expr(p, primary)
+	rhs := parse_expr(p, unary)
+
+	return ast.PrefixExpr{
+		Operator:  operatorToken,
+		RightExpr: rhs,
+	}
+}
+
+// typeof binds looser than member access and calls: typeof user.name
+func parse_typeof_expr(p *parser) ast.Expr {
+	operatorToken := p.advance()
+	rhs := parse_expr(p, unary)
 
 	return ast.PrefixExpr{
 		Operator:  operatorToken,
@@ -104,14 +134,19 @@ func parse_struct_instantiation_expr(p *parser, left ast.Expr, bp binding_power)
 
 	var structName = helpers.ExpectType[ast.SymbolExpr](left).Value
 	var properties = map[string]ast.Expr{}
-	p.expect(lexer.OPEN_CURLY)
+	var order []string
+	start := p.expect(lexer.OPEN_CURLY)
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
 		var propertyName = p.expect(lexer.IDENTIFIER).Value
 		p.expect(lexer.COLON)
 		expr := parse_expr(p, logical)
 
+		if _, exists := properties[propertyName]; exists {
+			panic(fmt.Sprintf("Property %s has already been given in instantiation of %s", propertyName, structName))
+		}
 		properties[propertyName] = expr
+		order = append(order, propertyName)
 
 		if p.currentTokenKind() != lexer.CLOSE_CURLY {
 			p.expect(lexer.COMMA)
@@ -123,6 +158,9 @@ func parse_struct_instantiation_expr(p *parser, left ast.Expr, bp binding_power)
 	return ast.StructInstantiationExpr{
 		StructName: structName,
 		Properties: properties,
+		Order:      order,
+		Line:       start.Line,
+		Column:     start.Column,
 	}
 }
 
@@ -130,7 +168,7 @@ func parse_array_instantiation_expr(p *parser) ast.Expr {
 	var underlyingType ast.Type
 	var contents = []ast.Expr{}
 
-	p.expect(lexer.OPEN_BRACKET)
+	start := p.expect(lexer.OPEN_BRACKET)
 	p.expect(lexer.CLOSE_BRACKET)
 
 	underlyingType = parse_type(p, default_bp)
@@ -149,63 +187,146 @@ func parse_array_instantiation_expr(p *parser) ast.Expr {
 	return ast.ArrayInstantiationExpr{
 		Underlying: underlyingType,
 		Contents:   contents,
+		Line:       start.Line,
+		Column:     start.Column,
+	}
+}
+
+func parse_map_instantiation_expr(p *parser) ast.Expr {
+	var entries = []ast.MapEntry{}
+
+	mapType := parse_map_type(p).(ast.MapType)
+
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		key := parse_expr(p, logical)
+		p.expectError(lexer.COLON, "Expected to find colon following key inside map instantiation")
+		value := parse_expr(p, logical)
+
+		entries = append(entries, ast.MapEntry{Key: key, Value: value})
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY {
+			p.expect(lexer.COMMA)
+		}
+	}
+
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.MapInstantiationExpr{
+		KeyType:   mapType.Key,
+		ValueType: mapType.Value,
+		Entries:   entries,
+	}
+}
+
+func parse_set_instantiation_expr(p *parser) ast.Expr {
+	var contents = []ast.Expr{}
+
+	setType := parse_set_type(p).(ast.SetType)
+
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		contents = append(contents, parse_expr(p, logical))
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY {
+			p.expect(lexer.COMMA)
+		}
+	}
+
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.SetInstantiationExpr{
+		Underlying: setType.Underlying,
+		Contents:   contents,
 	}
 }
 
 func parse_member_access_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
-	p.expect(lexer.DOT)
-	memberName := p.expect(lexer.IDENTIFIER).Value
+	start := p.expect(lexer.DOT)
+
+	// keywords such as set or map are valid member names
+	var memberName string
+	if p.currentToken().IsKeyword() {
+		memberName = p.advance().Value
+	} else {
+		memberName = p.expect(lexer.IDENTIFIER).Value
+	}
 
 	return ast.MemberAccessExpr{
 		Struct: left,
 		Member: memberName,
+		Line:   start.Line,
+		Column: start.Column,
 	}
 }
 
+// parse_array_access_expr parses a[i] as well as slices such as a[1:],
+// a[:-1] and a[::2]
 func parse_array_access_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	p.expect(lexer.OPEN_BRACKET)
-	var prev = false
-	var rest = false
 
 	if p.currentTokenKind() == lexer.CLOSE_BRACKET {
 		panic("Index expected after open bracket.")
 	}
 
-	if p.currentTokenKind() == lexer.COLON {
-		p.expect(lexer.COLON)
-		prev = true
+	var index ast.Expr
+	if p.currentTokenKind() != lexer.COLON {
+		index = parse_expr(p, default_bp)
+	}
 
+	if p.currentTokenKind() != lexer.COLON {
+		p.expect(lexer.CLOSE_BRACKET)
+		return ast.ArrayAccessExpr{Array: left, Index: index}
 	}
 
-	index := parse_expr(p, bp)
+	slice := ast.SliceExpr{Array: left, Start: index}
+	p.expect(lexer.COLON)
+	slice.End = parse_slice_bound(p)
 
 	if p.currentTokenKind() == lexer.COLON {
-		p.expect(lexer.COLON)
-		rest = true
-
+		p.advance()
+		slice.Step = parse_slice_bound(p)
 	}
 
 	p.expect(lexer.CLOSE_BRACKET)
+	return slice
+}
 
-	return ast.ArrayAccessExpr{
-		Array: left,
-		Index: index,
-		Rest:  rest,
-		Prev:  prev,
+func parse_slice_bound(p *parser) ast.Expr {
+	if p.currentTokenKind() == lexer.COLON || p.currentTokenKind() == lexer.CLOSE_BRACKET {
+		return nil
 	}
-
+	return parse_expr(p, default_bp)
 }
 
+// parse_grouping_expr parses either a parenthesised expression or, when a
+// comma follows the first expression, a tuple such as (a, b) or (a,)
 func parse_grouping_expr(p *parser) ast.Expr {
 	p.advance()
 	expr := parse_expr(p, default_bp)
+
+	if p.currentTokenKind() != lexer.COMMA {
+		p.expect(lexer.CLOSE_PAREN)
+		return expr
+	}
+
+	elements := []ast.Expr{expr}
+	for p.currentTokenKind() == lexer.COMMA {
+		p.advance()
+		if p.currentTokenKind() == lexer.CLOSE_PAREN {
+			break
+		}
+		elements = append(elements, parse_expr(p, default_bp))
+	}
 	p.expect(lexer.CLOSE_PAREN)
-	return expr
+
+	return ast.TupleExpr{Elements: elements}
 }
 
 func parse_call_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	var functionName string
 	var parentStruct ast.Expr
+	var callee ast.Expr
 
 	switch expr := left.(type) {
 	case ast.SymbolExpr:
@@ -213,9 +334,11 @@ func parse_call_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	case ast.MemberAccessExpr:
 		functionName = expr.Member
 		parentStruct = expr.Struct
+	default:
+		callee = left
 	}
 
-	p.expect(lexer.OPEN_PAREN) // Consume '('
+	start := p.expect(lexer.OPEN_PAREN) // Consume '('
 
 	// Parse arguments
 	args := parse_call_params_list(p)
@@ -225,15 +348,38 @@ func parse_call_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
 	return ast.CallExpr{
 		FunctionName: functionName,
 		Struct:       parentStruct,
+		Callee:       callee,
 		Arguments:    args,
+		Line:         start.Line,
+		Column:       start.Column,
 	}
 }
 
+// parse_call_params_list parses positional, named (`port: 5432`) and
+// spread (`...args`) call arguments
 func parse_call_params_list(p *parser) []ast.Expr {
 	var exprs []ast.Expr
+	var seenNamed = false
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
-		expr := parse_expr(p, default_bp)
+		var expr ast.Expr
+
+		if p.currentTokenKind() == lexer.DOT_DOT_DOT {
+			p.advance()
+			expr = ast.SpreadExpr{Value: parse_expr(p, default_bp)}
+		} else if p.currentTokenKind() == lexer.IDENTIFIER && p.tokens[p.pos+1].Kind == lexer.COLON {
+			name := p.advance().Value
+			p.expect(lexer.COLON)
+			expr = ast.NamedArgumentExpr{Name: name, Value: parse_expr(p, default_bp)}
+			seenNamed = true
+		} else {
+			expr = parse_expr(p, default_bp)
+		}
+
+		if _, isNamed := expr.(ast.NamedArgumentExpr); seenNamed && !isNamed {
+			panic("Positional arguments cannot follow named arguments")
+		}
+
 		exprs = append(exprs, expr)
 
 		if p.currentTokenKind() != lexer.CLOSE_PAREN {
@@ -243,3 +389,44 @@ func parse_call_params_list(p *parser) []ast.Expr {
 
 	return exprs
 }
+
+func parse_match_expr(p *parser) ast.Expr {
+	start := p.expect(lexer.MATCH)
+	p.expect(lexer.OPEN_PAREN)
+	subject := parse_expr(p, default_bp)
+	p.expect(lexer.CLOSE_PAREN)
+
+	var arms = []ast.MatchArm{}
+
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		var arm = ast.MatchArm{Pattern: parse_pattern(p)}
+
+		if p.currentTokenKind() == lexer.IF {
+			p.advance()
+			arm.Guard = parse_expr(p, default_bp)
+		}
+
+		p.expectError(lexer.FAT_ARROW, "Expected '=>' after pattern inside match arm")
+
+		if p.currentTokenKind() == lexer.OPEN_CURLY {
+			arm.Body = parse_block_stmt(p)
+		} else {
+			arm.Body = ast.ExpressionStmt{Expression: parse_expr(p, assignment)}
+		}
+
+		arms = append(arms, arm)
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.MatchExpr{
+		Subject: subject,
+		Arms:    arms,
+		Line:    start.Line,
+		Column:  start.Column,
+	}
+}
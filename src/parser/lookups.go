@@ -13,9 +13,14 @@ const (
 	assignment
 	logical
 	relational
+	bitwise_or
+	bitwise_xor
+	bitwise_and
+	shift
 	additive
 	multiplicative
 	unary
+	exponent
 	call
 	member
 	primary
@@ -41,8 +46,9 @@ func led(kind lexer.TokenKind, bp binding_power, led_fn led_handler) {
 
 }
 
+// nud leaves the binding power alone, it only matters for tokens following
+// an expression, where - and ( are infix operators
 func nud(kind lexer.TokenKind, _ binding_power, nud_fn nud_handler) {
-	bp_lu[kind] = primary
 	nud_lu[kind] = nud_fn
 }
 
@@ -56,6 +62,9 @@ func createTokenLookups() {
 	led(lexer.ASSIGNMENT, assignment, parse_assignment_expr)
 	led(lexer.PLUS_EQUALS, assignment, parse_assignment_expr)
 	led(lexer.MINUS_EQUALS, assignment, parse_assignment_expr)
+	led(lexer.STAR_EQUALS, assignment, parse_assignment_expr)
+	led(lexer.SLASH_EQUALS, assignment, parse_assignment_expr)
+	led(lexer.PERCENT_EQUALS, assignment, parse_assignment_expr)
 	led(lexer.PLUS_PLUS, assignment, parse_assignment_expr)
 	led(lexer.MINUS_MINUS, assignment, parse_assignment_expr)
 
@@ -70,15 +79,26 @@ func createTokenLookups() {
 	led(lexer.EQUALS, relational, parse_binary_expr)
 	led(lexer.NOT_EQUALS, relational, parse_binary_expr)
 
+	led(lexer.PIPE, bitwise_or, parse_binary_expr)
+	led(lexer.CARET, bitwise_xor, parse_binary_expr)
+	led(lexer.AMPERSAND, bitwise_and, parse_binary_expr)
+	led(lexer.SHIFT_LEFT, shift, parse_binary_expr)
+	led(lexer.SHIFT_RIGHT, shift, parse_binary_expr)
+
 	led(lexer.PLUS, additive, parse_binary_expr)
 	led(lexer.DASH, additive, parse_binary_expr)
 
 	led(lexer.STAR, multiplicative, parse_binary_expr)
 	led(lexer.SLASH, multiplicative, parse_binary_expr)
 	led(lexer.PERCENT, multiplicative, parse_binary_expr)
+	led(lexer.DIV, multiplicative, parse_binary_expr)
+
+	led(lexer.STAR_STAR, exponent, parse_exponent_expr)
 
 	nud(lexer.DASH, unary, parse_prefix_expr)
 	nud(lexer.NOT, unary, parse_prefix_expr)
+	nud(lexer.TILDE, unary, parse_prefix_expr)
+	nud(lexer.TYPEOF, unary, parse_typeof_expr)
 
 	led(lexer.OPEN_CURLY, call, parse_struct_instantiation_expr)
 	led(lexer.OPEN_PAREN, call, parse_call_expr)
@@ -87,10 +107,13 @@ func createTokenLookups() {
 	led(lexer.OPEN_BRACKET, member, parse_array_access_expr)
 
 	nud(lexer.OPEN_BRACKET, primary, parse_array_instantiation_expr)
+	nud(lexer.MAP, primary, parse_map_instantiation_expr)
+	nud(lexer.SET, primary, parse_set_instantiation_expr)
 	nud(lexer.OPEN_PAREN, primary, parse_grouping_expr)
 	nud(lexer.NUMBER, primary, parse_primary_expr)
 	nud(lexer.STRING, primary, parse_primary_expr)
 	nud(lexer.IDENTIFIER, primary, parse_primary_expr)
+	nud(lexer.MATCH, primary, parse_match_expr)
 
 	stmt(lexer.IMPORT, default_bp, parse_import_stmt)
 	stmt(lexer.CONST, default_bp, parse_var_decl_stmt)
@@ -100,9 +123,16 @@ func createTokenLookups() {
 	stmt(lexer.RETURN, default_bp, parse_return_stmt)
 	stmt(lexer.BREAK, default_bp, parse_break_stmt)
 	stmt(lexer.IF, default_bp, parse_if_stmt)
+	stmt(lexer.SWITCH, default_bp, parse_switch_stmt)
 	stmt(lexer.WHILE, default_bp, parse_while_stmt)
+	stmt(lexer.DO, default_bp, parse_do_while_stmt)
+	stmt(lexer.LOOP, default_bp, parse_loop_stmt)
 	stmt(lexer.FOREACH, default_bp, parse_foreach_stmt)
 	stmt(lexer.FOR, default_bp, parse_for_stmt)
 	stmt(lexer.IMPL, default_bp, parse_struct_impl_stmt)
+	stmt(lexer.ENUM, default_bp, parse_enum_decl_stmt)
+	stmt(lexer.MATCH, default_bp, parse_match_stmt)
+	stmt(lexer.TYPE, default_bp, parse_type_decl_stmt)
+	stmt(lexer.EXPORT, default_bp, parse_export_stmt)
 
 }
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"fmt"
+	"shiplang/src/ast"
+	"shiplang/src/lexer"
+	"strconv"
+)
+
+// parse_pattern parses a single match arm pattern:
+//
+//	_                    wildcard
+//	1, -1, "a", true     literal
+//	name                 binding
+//	Enum.Variant(a, _)   variant, fields are patterns themselves
+//	(a, b)               tuple
+//	[first, ...rest]     array, with an optional trailing rest binding
+//	{name, age: a}       struct fields, optionally prefixed by the struct name
+func parse_pattern(p *parser) ast.Pattern {
+	switch p.currentTokenKind() {
+	case lexer.OPEN_PAREN:
+		return parse_tuple_pattern(p)
+
+	case lexer.OPEN_BRACKET:
+		return parse_array_pattern(p)
+
+	case lexer.OPEN_CURLY:
+		return parse_struct_pattern(p, "")
+
+	case lexer.NUMBER, lexer.STRING:
+		return ast.LiteralPattern{Value: parse_primary_expr(p)}
+
+	case lexer.DASH:
+		p.advance()
+		number, _ := strconv.ParseFloat(p.expect(lexer.NUMBER).Value, 64)
+		return ast.LiteralPattern{Value: ast.NumberExpr{Value: -number}}
+
+	case lexer.IDENTIFIER:
+		name := p.advance().Value
+
+		switch name {
+		case "_":
+			return ast.WildcardPattern{}
+		case "true", "false", "null":
+			return ast.LiteralPattern{Value: ast.SymbolExpr{Value: name}}
+		}
+
+		if p.currentTokenKind() == lexer.OPEN_CURLY {
+			return parse_struct_pattern(p, name)
+		}
+
+		if p.currentTokenKind() != lexer.DOT {
+			return ast.BindingPattern{Name: name}
+		}
+
+		p.expect(lexer.DOT)
+		variant := p.expectError(lexer.IDENTIFIER, "Expected variant name after '.' in pattern").Value
+		fields := make([]ast.Pattern, 0)
+
+		if p.currentTokenKind() == lexer.OPEN_PAREN {
+			p.advance()
+			for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
+				fields = append(fields, parse_pattern(p))
+
+				if p.currentTokenKind() != lexer.CLOSE_PAREN {
+					p.expect(lexer.COMMA)
+				}
+			}
+			p.expect(lexer.CLOSE_PAREN)
+		}
+
+		return ast.VariantPattern{EnumName: name, Variant: variant, Fields: fields}
+
+	default:
+		panic(fmt.Sprintf("Cannot create pattern from %s\n", lexer.TokenKindString(p.currentTokenKind())))
+	}
+}
+
+func parse_tuple_pattern(p *parser) ast.Pattern {
+	var elements = []ast.Pattern{}
+
+	p.expect(lexer.OPEN_PAREN)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
+		elements = append(elements, parse_pattern(p))
+
+		if p.currentTokenKind() != lexer.CLOSE_PAREN {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.CLOSE_PAREN)
+
+	return ast.TuplePattern{Elements: elements}
+}
+
+func parse_array_pattern(p *parser) ast.Pattern {
+	var pattern = ast.ArrayPattern{Elements: []ast.Pattern{}}
+
+	p.expect(lexer.OPEN_BRACKET)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_BRACKET {
+		if p.currentTokenKind() == lexer.DOT_DOT_DOT {
+			p.advance()
+			pattern.Rest = p.expectError(lexer.IDENTIFIER, "Expected binding name after '...' in array pattern").Value
+			pattern.HasRest = true
+
+			if p.currentTokenKind() != lexer.CLOSE_BRACKET {
+				panic("Rest binding must be the last element of an array pattern")
+			}
+			break
+		}
+
+		pattern.Elements = append(pattern.Elements, parse_pattern(p))
+
+		if p.currentTokenKind() != lexer.CLOSE_BRACKET {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.CLOSE_BRACKET)
+
+	return pattern
+}
+
+func parse_struct_pattern(p *parser, structName string) ast.Pattern {
+	var fields = []ast.StructFieldPattern{}
+
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		fieldName := p.expectError(lexer.IDENTIFIER, "Expected property name inside struct pattern").Value
+		var fieldPattern ast.Pattern = ast.BindingPattern{Name: fieldName}
+
+		if p.currentTokenKind() == lexer.COLON {
+			p.advance()
+			fieldPattern = parse_pattern(p)
+		}
+
+		fields = append(fields, ast.StructFieldPattern{Name: fieldName, Pattern: fieldPattern})
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.StructPattern{StructName: structName, Fields: fields}
+}
@@ -23,7 +23,14 @@ func parse_var_decl_stmt(p *parser) ast.Stmt {
 	var explicitType ast.Type
 	var assignedVal ast.Expr
 
-	isConst := p.advance().Kind == lexer.CONST
+	keyword := p.advance()
+	isConst := keyword.Kind == lexer.CONST
+
+	switch p.currentTokenKind() {
+	case lexer.OPEN_PAREN, lexer.OPEN_BRACKET, lexer.OPEN_CURLY:
+		return parse_destructuring_decl_stmt(p, isConst, keyword)
+	}
+
 	varName := p.expectError(lexer.IDENTIFIER, "Inside variable declaration expected to find variable name").Value
 
 	if p.currentTokenKind() == lexer.COLON {
@@ -49,6 +56,34 @@ func parse_var_decl_stmt(p *parser) ast.Stmt {
 		IsConstant:    isConst,
 		VarName:       varName,
 		AssignedValue: assignedVal,
+		Line:          keyword.Line,
+		Column:        keyword.Column,
+	}
+}
+
+// let (q, r) = divmod(7, 2);
+// let [first, ...rest] = arr;
+// let {name, age} = person;
+func parse_destructuring_decl_stmt(p *parser, isConst bool, start lexer.Token) ast.Stmt {
+	var explicitType ast.Type
+	pattern := parse_pattern(p)
+
+	if p.currentTokenKind() == lexer.COLON {
+		p.advance()
+		explicitType = parse_type(p, default_bp)
+	}
+
+	p.expectError(lexer.ASSIGNMENT, "Destructuring declaration must provide a value")
+	assignedVal := parse_expr(p, assignment)
+	p.expect(lexer.SEMI_COLON)
+
+	return ast.VarDeclStmt{
+		Pattern:       pattern,
+		ExplicitType:  explicitType,
+		IsConstant:    isConst,
+		AssignedValue: assignedVal,
+		Line:          start.Line,
+		Column:        start.Column,
 	}
 }
 
@@ -59,7 +94,7 @@ func parse_if_stmt(p *parser) ast.Stmt {
 	p.expect(lexer.CLOSE_PAREN)
 	body := parse_block_stmt(p)
 
-	elifBodies := make(map[ast.Expr]ast.BlockStmt)
+	var elifBodies []ast.ElifBody
 
 	var elseBody ast.BlockStmt
 
@@ -72,7 +107,7 @@ func parse_if_stmt(p *parser) ast.Stmt {
 			elifCondition := parse_expr(p, default_bp)
 			p.expect(lexer.CLOSE_PAREN)
 			elifBody := parse_block_stmt(p)
-			elifBodies[elifCondition] = elifBody.(ast.BlockStmt)
+			elifBodies = append(elifBodies, ast.ElifBody{Condition: elifCondition, Body: elifBody.(ast.BlockStmt)})
 		} else {
 			// Parse else
 			elseBody = parse_block_stmt(p).(ast.BlockStmt)
@@ -88,11 +123,85 @@ func parse_if_stmt(p *parser) ast.Stmt {
 	}
 }
 
+// parse_switch_stmt parses
+//
+//	switch (subject) { case a, b: ... case lo..hi: ... default: ... }
+//
+// a case body runs until the next case, there is no fallthrough
+func parse_switch_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.SWITCH)
+	p.expect(lexer.OPEN_PAREN)
+	subject := parse_expr(p, default_bp)
+	p.expect(lexer.CLOSE_PAREN)
+	p.expect(lexer.OPEN_CURLY)
+
+	var cases []ast.SwitchCase
+	var defaultBody ast.BlockStmt
+	hasDefault := false
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		if p.currentTokenKind() == lexer.DEFAULT {
+			p.advance()
+			if hasDefault {
+				panic("Switch can only have one default case")
+			}
+			p.expectError(lexer.COLON, "Expected ':' after default")
+			defaultBody = parse_case_body(p)
+			hasDefault = true
+			continue
+		}
+
+		p.expectError(lexer.CASE, "Expected case or default inside switch")
+		var labels []ast.CaseLabel
+		for {
+			label := ast.CaseLabel{Value: parse_expr(p, default_bp)}
+
+			// .. only has a meaning here, it makes the label a range
+			if rng, ok := label.Value.(ast.BinaryExpr); ok && rng.Operator.Kind == lexer.DOT_DOT {
+				label = ast.CaseLabel{Value: rng.Left, Upper: rng.Right}
+			}
+			labels = append(labels, label)
+
+			if p.currentTokenKind() != lexer.COMMA {
+				break
+			}
+			p.advance()
+		}
+		p.expectError(lexer.COLON, "Expected ':' after case values")
+
+		cases = append(cases, ast.SwitchCase{Labels: labels, Body: parse_case_body(p)})
+	}
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.SwitchStmt{
+		Subject: subject,
+		Cases:   cases,
+		Default: defaultBody,
+		Line:    start.Line,
+		Column:  start.Column,
+	}
+}
+
+func parse_case_body(p *parser) ast.BlockStmt {
+	var body []ast.Stmt
+	for p.hasTokens() {
+		kind := p.currentTokenKind()
+		if kind == lexer.CASE || kind == lexer.DEFAULT || kind == lexer.CLOSE_CURLY {
+			break
+		}
+		body = append(body, parse_stmt(p))
+	}
+	return ast.BlockStmt{Body: body}
+}
+
 func parse_struct_decl_stmt(p *parser) ast.Stmt {
 
-	p.expect(lexer.STRUCT)
+	start := p.expect(lexer.STRUCT)
 	var properties = map[string]ast.StructProperty{}
+	var order []string
+	var embedded []string
 	var structName = p.expect(lexer.IDENTIFIER).Value
+	var typeParams = parse_type_params(p)
 
 	p.expect(lexer.OPEN_CURLY)
 
@@ -100,11 +209,61 @@ func parse_struct_decl_stmt(p *parser) ast.Stmt {
 
 		var propertyName string
 
+		// pub is only a keyword in front of a property name
+		public := false
+		if p.currentTokenKind() == lexer.IDENTIFIER && p.currentToken().Value == "pub" && p.tokens[p.pos+1].Kind == lexer.IDENTIFIER {
+			p.advance()
+			public = true
+		}
+
+		// a bare struct name embeds that struct
+		if p.currentTokenKind() == lexer.IDENTIFIER && p.tokens[p.pos+1].Kind == lexer.SEMI_COLON {
+			embeddedName := p.advance().Value
+			p.advance()
+
+			if _, exists := properties[embeddedName]; exists {
+				panic(fmt.Sprintf("Property %s has already been defined in struct declaration", embeddedName))
+			}
+
+			properties[embeddedName] = ast.StructProperty{
+				Type:     ast.SymbolType{Name: embeddedName},
+				Required: true,
+				Public:   public,
+			}
+			order = append(order, embeddedName)
+			embedded = append(embedded, embeddedName)
+			continue
+		}
+
 		if p.currentTokenKind() == lexer.IDENTIFIER {
+			// required and readonly are only keywords in front of a property
+			// name
+			required, readonly := false, false
+			for p.tokens[p.pos+1].Kind == lexer.IDENTIFIER {
+				if p.currentToken().Value == "required" && !required {
+					required = true
+				} else if p.currentToken().Value == "readonly" && !readonly {
+					readonly = true
+				} else {
+					break
+				}
+				p.advance()
+			}
+
 			propertyName = p.expect(lexer.IDENTIFIER).Value
 			p.expectError(lexer.COLON, "Expected to find colon following property name inside struct declaration")
 
 			structType := parse_type(p, default_bp)
+
+			var defaultValue ast.Expr
+			if p.currentTokenKind() == lexer.ASSIGNMENT {
+				p.advance()
+				defaultValue = parse_expr(p, default_bp)
+
+				if required {
+					panic(fmt.Sprintf("Required property %s cannot have a default value", propertyName))
+				}
+			}
 			p.expect(lexer.SEMI_COLON)
 
 			_, exists := properties[propertyName]
@@ -114,8 +273,13 @@ func parse_struct_decl_stmt(p *parser) ast.Stmt {
 			}
 
 			properties[propertyName] = ast.StructProperty{
-				Type: structType,
+				Type:     structType,
+				Default:  defaultValue,
+				Required: required,
+				Public:   public,
+				Readonly: readonly,
 			}
+			order = append(order, propertyName)
 
 			continue
 		}
@@ -127,7 +291,12 @@ func parse_struct_decl_stmt(p *parser) ast.Stmt {
 
 	return ast.StructDeclStmt{
 		StructName: structName,
+		TypeParams: typeParams,
 		Properties: properties,
+		Order:      order,
+		Embedded:   embedded,
+		Line:       start.Line,
+		Column:     start.Column,
 	}
 }
 
@@ -144,6 +313,35 @@ func parse_struct_impl_stmt(p *parser) ast.Stmt {
 
 }
 
+// export fn area() {} makes a top level declaration importable from other
+// files
+func parse_export_stmt(p *parser) ast.Stmt {
+	p.expect(lexer.EXPORT)
+
+	switch decl := parse_stmt(p).(type) {
+	case ast.FnDeclStmt:
+		decl.Exported = true
+		return decl
+	case ast.StructDeclStmt:
+		decl.Exported = true
+		return decl
+	case ast.EnumDeclStmt:
+		decl.Exported = true
+		return decl
+	case ast.TypeDeclStmt:
+		decl.Exported = true
+		return decl
+	case ast.VarDeclStmt:
+		if decl.Pattern != nil {
+			panic("Destructuring declarations cannot be exported, declare each exported name separately")
+		}
+		decl.Exported = true
+		return decl
+	default:
+		panic("Only fn, struct, enum, type, const and let declarations can be exported")
+	}
+}
+
 func parse_import_stmt(p *parser) ast.Stmt {
 	p.expect(lexer.IMPORT)
 
@@ -187,39 +385,84 @@ func parse_import_stmt(p *parser) ast.Stmt {
 }
 
 // fn hello(){}
+// fn area(r: number): number {}
+// fn first<T>(xs: []T): T {}
 // parse_fn_decl_stmt parses a function declaration statement
 func parse_fn_decl_stmt(p *parser) ast.Stmt {
 	p.expect(lexer.FN)
 
 	fnName := p.expect(lexer.IDENTIFIER).Value
+	typeParams := parse_type_params(p)
 
 	p.expect(lexer.OPEN_PAREN)
 	parameters := parse_fn_params(p)
 	p.expect(lexer.CLOSE_PAREN)
 
+	var returnType ast.Type
+	if p.currentTokenKind() == lexer.COLON {
+		p.advance()
+		returnType = parse_type(p, default_bp)
+	}
+
 	body := parse_block_stmt(p)
 
 	return ast.FnDeclStmt{
 		FnName:     fnName,
+		TypeParams: typeParams,
 		Parameters: parameters,
+		ReturnType: returnType,
 		Body:       body.(ast.BlockStmt),
 	}
 }
 
-// parse_fn_params parses function parameters
+// parse_fn_params parses function parameters, including defaults
+// (`port: number = 8080`) and a trailing variadic (`...opts: []string`)
 func parse_fn_params(p *parser) []ast.Parameter {
 	params := make([]ast.Parameter, 0)
 
 	for p.currentTokenKind() != lexer.CLOSE_PAREN && p.hasTokens() {
+		isVariadic := false
+		if p.currentTokenKind() == lexer.DOT_DOT_DOT {
+			p.advance()
+			isVariadic = true
+		}
+
 		paramName := p.expect(lexer.IDENTIFIER).Value
 		var pType ast.Type = ast.SymbolType{Name: "any"}
 
+		if isVariadic {
+			pType = ast.ArrayType{Underlying: pType}
+		}
+
 		if p.currentTokenKind() == lexer.COLON {
 			p.advance()
 			pType = parse_type(p, default_bp)
 		}
 
-		params = append(params, ast.Parameter{Name: paramName, Type: pType})
+		if _, isArray := pType.(ast.ArrayType); isVariadic && !isArray {
+			panic(fmt.Sprintf("Variadic parameter %s must have an array type", paramName))
+		}
+
+		var defaultValue ast.Expr
+		if p.currentTokenKind() == lexer.ASSIGNMENT {
+			if isVariadic {
+				panic(fmt.Sprintf("Variadic parameter %s cannot have a default value", paramName))
+			}
+			p.advance()
+			defaultValue = parse_expr(p, assignment)
+		}
+
+		if len(params) > 0 {
+			prev := params[len(params)-1]
+			if prev.IsVariadic {
+				panic(fmt.Sprintf("Parameter %s cannot follow variadic parameter %s", paramName, prev.Name))
+			}
+			if prev.Default != nil && defaultValue == nil && !isVariadic {
+				panic(fmt.Sprintf("Parameter %s without a default cannot follow parameter %s with a default", paramName, prev.Name))
+			}
+		}
+
+		params = append(params, ast.Parameter{Name: paramName, Type: pType, Default: defaultValue, IsVariadic: isVariadic})
 
 		if p.currentTokenKind() != lexer.CLOSE_PAREN {
 			p.expect(lexer.COMMA) // Consume ',' between parameters
@@ -243,12 +486,18 @@ func parse_block_stmt(p *parser) ast.Stmt {
 }
 
 func parse_return_stmt(p *parser) ast.Stmt {
-	p.advance() // eat the return token
-	returnval := parse_expr(p, assignment)
+	start := p.advance() // eat the return token
+
+	var returnval ast.Expr
+	if p.currentTokenKind() != lexer.SEMI_COLON {
+		returnval = parse_expr(p, assignment)
+	}
 	p.expect(lexer.SEMI_COLON)
 
 	return ast.ReturnStmt{
-		Value: returnval,
+		Value:  returnval,
+		Line:   start.Line,
+		Column: start.Column,
 	}
 }
 
@@ -272,43 +521,104 @@ func parse_while_stmt(p *parser) ast.Stmt {
 	}
 }
 
+func parse_do_while_stmt(p *parser) ast.Stmt {
+	p.expect(lexer.DO)
+	body := parse_block_stmt(p).(ast.BlockStmt)
+
+	p.expectError(lexer.WHILE, "Expected while after the body of a do loop")
+	p.expect(lexer.OPEN_PAREN)
+	cond := parse_expr(p, default_bp)
+	p.expect(lexer.CLOSE_PAREN)
+	p.expect(lexer.SEMI_COLON)
+
+	return ast.DoWhileStmt{
+		Body:      body,
+		Condition: cond,
+	}
+}
+
+func parse_loop_stmt(p *parser) ast.Stmt {
+	p.expect(lexer.LOOP)
+
+	return ast.LoopStmt{
+		Body: parse_block_stmt(p).(ast.BlockStmt),
+	}
+}
+
 func parse_foreach_stmt(p *parser) ast.Stmt {
-	p.expect(lexer.FOREACH)
+	start := p.expect(lexer.FOREACH)
 	p.expect(lexer.OPEN_PAREN)
-	iterator := p.expect(lexer.IDENTIFIER).Value
+
+	var keyIterator, iterator string
+	var keyType, iteratorType ast.Type
+
+	if p.currentTokenKind() == lexer.OPEN_PAREN {
+		// foreach ((key, value) in collection), key is the index for
+		// arrays, strings and sets and the field name for structs
+		p.advance()
+		keyIterator, keyType = parse_foreach_iterator(p)
+		p.expect(lexer.COMMA)
+		iterator, iteratorType = parse_foreach_iterator(p)
+		p.expect(lexer.CLOSE_PAREN)
+	} else {
+		iterator, iteratorType = parse_foreach_iterator(p)
+	}
+
 	p.expect(lexer.IN)
 	collection := parse_expr(p, default_bp)
 	p.expect(lexer.CLOSE_PAREN)
 	body := parse_block_stmt(p).(ast.BlockStmt)
 
 	return ast.ForeachStmt{
-		Iterator:   iterator,
-		Collection: collection,
-		Body:       body,
+		KeyIterator:  keyIterator,
+		KeyType:      keyType,
+		Iterator:     iterator,
+		IteratorType: iteratorType,
+		Collection:   collection,
+		Body:         body,
+		Line:         start.Line,
+		Column:       start.Column,
+	}
+}
+
+// parse_foreach_iterator parses a loop variable with an optional type: p: Point
+func parse_foreach_iterator(p *parser) (string, ast.Type) {
+	name := p.expect(lexer.IDENTIFIER).Value
+
+	if p.currentTokenKind() != lexer.COLON {
+		return name, nil
 	}
+	p.advance()
+	return name, parse_type(p, default_bp)
 }
 
 func parse_for_stmt(p *parser) ast.Stmt {
 	p.expect(lexer.FOR)
 	p.expect(lexer.OPEN_PAREN)
 
+	// each clause may be left out: for (;;) { }
 	var init ast.Stmt
 	if p.currentTokenKind() != lexer.SEMI_COLON {
 		init = parse_stmt(p)
+	} else {
+		p.advance()
 	}
 
 	var cond ast.Expr
 	if p.currentTokenKind() != lexer.SEMI_COLON {
 		cond = parse_expr(p, default_bp)
-	} else {
-		panic("Expected condition expression in for loop")
 	}
 
 	p.expect(lexer.SEMI_COLON)
 
 	var post ast.Stmt
 	if p.currentTokenKind() != lexer.CLOSE_PAREN {
-		post = parse_stmt(p)
+		post = ast.ExpressionStmt{Expression: parse_expr(p, default_bp)}
+
+		// older code ends the post statement with a ;
+		if p.currentTokenKind() == lexer.SEMI_COLON {
+			p.advance()
+		}
 	}
 
 	p.expect(lexer.CLOSE_PAREN)
@@ -322,3 +632,76 @@ func parse_for_stmt(p *parser) ast.Stmt {
 		Body: body,
 	}
 }
+
+func parse_enum_decl_stmt(p *parser) ast.Stmt {
+	p.expect(lexer.ENUM)
+	var enumName = p.expect(lexer.IDENTIFIER).Value
+	var variants = []ast.EnumVariant{}
+	var seen = map[string]bool{}
+
+	p.expect(lexer.OPEN_CURLY)
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		variantName := p.expectError(lexer.IDENTIFIER, "Expected variant name inside enum declaration").Value
+
+		if seen[variantName] {
+			panic(fmt.Sprintf("Variant %s has already been defined in enum %s", variantName, enumName))
+		}
+		seen[variantName] = true
+
+		fields := make([]ast.Parameter, 0)
+
+		if p.currentTokenKind() == lexer.OPEN_PAREN {
+			p.advance()
+			fields = parse_fn_params(p)
+			p.expect(lexer.CLOSE_PAREN)
+		}
+
+		variants = append(variants, ast.EnumVariant{Name: variantName, Fields: fields})
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY {
+			p.expect(lexer.COMMA)
+		}
+	}
+
+	p.expect(lexer.CLOSE_CURLY)
+
+	return ast.EnumDeclStmt{
+		EnumName: enumName,
+		Variants: variants,
+	}
+}
+
+func parse_type_decl_stmt(p *parser) ast.Stmt {
+	p.expect(lexer.TYPE)
+	typeName := p.expectError(lexer.IDENTIFIER, "Expected type name inside type declaration").Value
+	distinct := false
+
+	p.expect(lexer.ASSIGNMENT)
+
+	// distinct is only meaningful here so it is not a reserved word
+	if p.currentToken().Kind == lexer.IDENTIFIER && p.currentToken().Value == "distinct" {
+		p.advance()
+		distinct = true
+	}
+
+	underlying := parse_type(p, default_bp)
+	p.expect(lexer.SEMI_COLON)
+
+	return ast.TypeDeclStmt{
+		TypeName:   typeName,
+		Underlying: underlying,
+		Distinct:   distinct,
+	}
+}
+
+// match used in statement position does not need a trailing semicolon
+func parse_match_stmt(p *parser) ast.Stmt {
+	expr := parse_match_expr(p)
+
+	if p.currentTokenKind() == lexer.SEMI_COLON {
+		p.advance()
+	}
+
+	return ast.ExpressionStmt{Expression: expr}
+}
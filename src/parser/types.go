@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"shiplang/src/ast"
 	"shiplang/src/lexer"
+	"slices"
 )
 
 type type_nud_handler func(p *parser) ast.Type
@@ -28,14 +29,88 @@ func type_nud(kind lexer.TokenKind, nud_fn type_nud_handler) {
 func createTokenTypeLookups() {
 	type_nud(lexer.IDENTIFIER, parse_symbol_type)
 	type_nud(lexer.OPEN_BRACKET, parse_array_type)
+	type_nud(lexer.MAP, parse_map_type)
+	type_nud(lexer.SET, parse_set_type)
+	type_nud(lexer.OPEN_PAREN, parse_tuple_type)
+	type_nud(lexer.QUESTION, parse_nullable_type)
+	type_nud(lexer.FN, parse_fn_type)
+
+	type_led(lexer.PIPE, primary, parse_union_type)
 }
 
 func parse_symbol_type(p *parser) ast.Type {
-	return ast.SymbolType{
-		Name: p.expect(lexer.IDENTIFIER).Value,
+	name := p.expect(lexer.IDENTIFIER).Value
+
+	if p.currentTokenKind() != lexer.LESS {
+		return ast.SymbolType{
+			Name: name,
+		}
+	}
+
+	var typeArgs = []ast.Type{}
+
+	p.expect(lexer.LESS)
+	for p.hasTokens() && p.currentTokenKind() != lexer.GREATER {
+		typeArgs = append(typeArgs, parse_type(p, default_bp))
+		split_shift_right(p)
+
+		if p.currentTokenKind() != lexer.GREATER {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.GREATER)
+
+	return ast.GenericType{
+		Name:     name,
+		TypeArgs: typeArgs,
 	}
 }
 
+// split_shift_right splits the >> closing nested type arguments, as in
+// Map<string, Array<number>>, into two >
+func split_shift_right(p *parser) {
+	if p.currentTokenKind() != lexer.SHIFT_RIGHT {
+		return
+	}
+
+	shift := p.currentToken()
+	closing := lexer.NewToken(lexer.GREATER, ">")
+	closing.Line = shift.Line
+	closing.Column = shift.Column + 1
+	p.tokens[p.pos] = closing
+	closing.Column = shift.Column
+	p.tokens = slices.Insert(p.tokens, p.pos, closing)
+}
+
+// parse_type_params parses the optional <A, B> list following the name of a
+// generic fn or struct declaration
+func parse_type_params(p *parser) []string {
+	var params = []string{}
+
+	if p.currentTokenKind() != lexer.LESS {
+		return params
+	}
+
+	p.expect(lexer.LESS)
+	for p.hasTokens() && p.currentTokenKind() != lexer.GREATER {
+		name := p.expectError(lexer.IDENTIFIER, "Expected type parameter name").Value
+
+		for _, existing := range params {
+			if existing == name {
+				panic(fmt.Sprintf("Type parameter %s has already been declared", name))
+			}
+		}
+		params = append(params, name)
+
+		if p.currentTokenKind() != lexer.GREATER {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.GREATER)
+
+	return params
+}
+
 func parse_array_type(p *parser) ast.Type {
 	p.advance()
 	p.expect(lexer.CLOSE_BRACKET)
@@ -45,6 +120,111 @@ func parse_array_type(p *parser) ast.Type {
 	}
 }
 
+func parse_map_type(p *parser) ast.Type {
+	p.expect(lexer.MAP)
+	p.expect(lexer.OPEN_BRACKET)
+	var keyType = parse_type(p, default_bp)
+	p.expect(lexer.CLOSE_BRACKET)
+	var valueType = parse_type(p, default_bp)
+	return ast.MapType{
+		Key:   keyType,
+		Value: valueType,
+	}
+}
+
+func parse_set_type(p *parser) ast.Type {
+	p.expect(lexer.SET)
+	p.expect(lexer.OPEN_BRACKET)
+	var underlyingType = parse_type(p, default_bp)
+	p.expect(lexer.CLOSE_BRACKET)
+	return ast.SetType{
+		Underlying: underlyingType,
+	}
+}
+
+func parse_fn_type(p *parser) ast.Type {
+	var params = []ast.Type{}
+	var returnType ast.Type
+
+	p.expect(lexer.FN)
+	p.expect(lexer.OPEN_PAREN)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
+		params = append(params, parse_type(p, default_bp))
+
+		if p.currentTokenKind() != lexer.CLOSE_PAREN {
+			p.expect(lexer.COMMA)
+		}
+	}
+	p.expect(lexer.CLOSE_PAREN)
+
+	if p.currentTokenKind() == lexer.COLON {
+		p.advance()
+		returnType = parse_type(p, default_bp)
+	}
+
+	return ast.FnType{
+		Params: params,
+		Return: returnType,
+	}
+}
+
+func parse_nullable_type(p *parser) ast.Type {
+	p.expect(lexer.QUESTION)
+	var underlyingType = parse_type(p, default_bp)
+
+	if _, isNullable := underlyingType.(ast.NullableType); isNullable {
+		panic("Type is already nullable")
+	}
+
+	return ast.NullableType{
+		Underlying: underlyingType,
+	}
+}
+
+// (number, string) is a tuple type while (number | string) only groups,
+// a one element tuple needs a trailing comma: (number,)
+func parse_tuple_type(p *parser) ast.Type {
+	var elements = []ast.Type{}
+	var trailingComma = false
+
+	p.expect(lexer.OPEN_PAREN)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
+		elements = append(elements, parse_type(p, default_bp))
+		trailingComma = false
+
+		if p.currentTokenKind() != lexer.CLOSE_PAREN {
+			p.expect(lexer.COMMA)
+			trailingComma = true
+		}
+	}
+	p.expect(lexer.CLOSE_PAREN)
+
+	if len(elements) == 1 && !trailingComma {
+		return elements[0]
+	}
+
+	return ast.TupleType{
+		Elements: elements,
+	}
+}
+
+// number | string | null, flattened into a single union
+func parse_union_type(p *parser, left ast.Type, bp binding_power) ast.Type {
+	p.expect(lexer.PIPE)
+	right := parse_type(p, bp)
+
+	members := []ast.Type{}
+	if union, ok := left.(ast.UnionType); ok {
+		members = append(members, union.Members...)
+	} else {
+		members = append(members, left)
+	}
+
+	return ast.UnionType{
+		Members: append(members, right),
+	}
+}
+
 func parse_type(p *parser, bp binding_power) ast.Type {
 	tokenKind := p.currentTokenKind()
 	nud_fn, exists := type_nud_lu[tokenKind]
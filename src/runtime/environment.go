@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"fmt"
+	"shiplang/src/ast"
 )
 
 type environment struct {
@@ -9,6 +10,12 @@ type environment struct {
 	Variables  map[string]Variable
 	StructDefs map[string]StructDef
 	Functions  map[string]Function
+	Types      map[string]*TypeSpec
+	Aliases    map[string]*TypeSpec
+	Distincts  map[string]*TypeSpec // underlying type of each distinct type
+	Exports    map[string]bool      // names other files may import, module scope only
+	Strict     bool
+	Call       bool // the scope of a function call
 }
 
 func NewEnv(parent *environment) *environment {
@@ -17,6 +24,9 @@ func NewEnv(parent *environment) *environment {
 		Variables:  make(map[string]Variable),
 		StructDefs: make(map[string]StructDef),
 		Functions:  make(map[string]Function),
+		Aliases:    make(map[string]*TypeSpec),
+		Distincts:  make(map[string]*TypeSpec),
+		Exports:    make(map[string]bool),
 	}
 
 	declareNativeFunctions(env)
@@ -30,16 +40,19 @@ func (e *environment) containsVar(varName string) bool {
 	return exists
 }
 
-func (e *environment) declareVar(varName string, value RuntimeVal, expectedType ValueType, isConst bool) RuntimeVal {
+func (e *environment) declareVar(varName string, value RuntimeVal, expectedType *TypeSpec, isConst bool) RuntimeVal {
 	if e.containsVar(varName) {
 		panic("Already has var")
 	}
 
-	if expectedType == "" {
+	if expectedType == nil {
 		expectedType = AnyType
 	}
 
 	if !checkType(value.Type(), expectedType) {
+		if value.Type().Equal(NullType) {
+			panic(fmt.Sprintf("Cannot declare %s of non-nullable type %s as null", varName, expectedType))
+		}
 		panic(fmt.Sprintf("expected %s got %s", expectedType, value.Type()))
 	}
 
@@ -66,9 +79,10 @@ func (e *environment) lookupVar(varName string) Variable {
 	return env.Variables[varName]
 }
 
-func (e *environment) declareStruct(structName string, properties map[string]ValueType) RuntimeVal {
-	s := StructDef{Name: structName, Properties: properties, Methods: make(map[string]Function)}
-	e.StructDefs[structName] = s
+func (e *environment) declareStruct(s StructDef) RuntimeVal {
+	s.Methods = make(map[string]Function)
+	s.Env = e
+	e.StructDefs[s.Name] = s
 	return s
 }
 
@@ -83,6 +97,9 @@ func (e *environment) implMethod(structName string, method Function) RuntimeVal
 		panic("Struct not defined")
 	}
 
+	// methods may refer to the type parameters of their struct
+	method.TypeParams = append(append([]string{}, e.StructDefs[structName].TypeParams...), method.TypeParams...)
+
 	e.StructDefs[structName].Methods[method.Name] = method
 	return method
 }
@@ -98,7 +115,7 @@ func (e *environment) declareFn(fn Function) RuntimeVal {
 	}
 
 	e.Functions[fn.Name] = fn
-	e.declareVar(fn.Name, fn, FunctionType, true)
+	e.declareVar(fn.Name, fn, fn.Type(), true)
 
 	return fn
 }
@@ -135,53 +152,333 @@ func (e *environment) lookupFn(fnName string) RuntimeVal {
 	return env.Functions[fnName]
 }
 
+// lookupCallable resolves the function a call by name refers to. The
+// nearest scope wins, whether it holds a function reference in a variable
+// or a declared function.
+func (e *environment) lookupCallable(name string) Function {
+	if variable, exists := e.Variables[name]; exists {
+		fn, ok := variable.Value.(Function)
+		if !ok {
+			panic(fmt.Sprintf("%s is not a function reference", name))
+		}
+		return fn
+	}
+
+	if fn, exists := e.Functions[name]; exists {
+		return fn
+	}
+
+	if e.Parent != nil {
+		return e.Parent.lookupCallable(name)
+	}
+
+	panic(fmt.Sprintf("Function %s not found.", name))
+}
+
+// inFunction reports whether e is inside the body of a function call.
+func (e *environment) inFunction() bool {
+	for env := e; env != nil; env = env.Parent {
+		if env.Call {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *environment) assignVar(varName string, value RuntimeVal) Variable {
 
 	env := e.resolveVar(varName)
 	variable := env.Variables[varName]
 
 	if variable.Constant {
-		panic("")
+		panic(fmt.Sprintf("Cannot assign to constant %s", varName))
 	}
 
 	if !checkType(value.Type(), variable.ExpectedType) {
-		panic("")
+		if value.Type().Equal(NullType) {
+			panic(fmt.Sprintf("Cannot assign null to %s of non-nullable type %s", varName, variable.ExpectedType))
+		}
+		panic(fmt.Sprintf("Cannot assign %s to %s of type %s", value.Type(), varName, variable.ExpectedType))
 	}
 
 	env.Variables[varName] = Variable{Value: value, ExpectedType: variable.ExpectedType, Constant: variable.Constant}
 	return variable
 }
 
-func (e *environment) assignStruct(varName string, memberName string, value RuntimeVal) RuntimeVal {
-	env := e.resolveVar(varName)
-	variable := env.Variables[varName]
+// assignMember stores value into a member of structVal, whose properties
+// are shared with every copy of it. binding names the variable structVal
+// was reached from.
+func (e *environment) assignMember(structVal Struct, binding string, memberName string, value RuntimeVal) RuntimeVal {
+	target := structVal
+	structDef := structVal.Def
 
-	structDef := e.lookupStruct(string(variable.Value.Type())).(StructDef)
-	structVal := variable.Value.(Struct)
-
-	_, memberExists := structDef.Properties[memberName]
+	expectedType, memberExists := structDef.Properties[memberName]
 	if !memberExists {
-		panic("")
+		// promoted properties are stored in the embedded struct, whose
+		// properties are shared with the embedding one
+		path := e.promotedPath(structDef, memberName)
+		if path == nil {
+			panic(fmt.Sprintf("Member %s not found in struct %s", memberName, structVal.Name))
+		}
+		target = embeddedValue(structVal, path)
+		structDef = target.Def
+		expectedType = structDef.Properties[memberName]
+	}
+	checkVisible(structDef, memberName, e)
+	checkMutable(target, binding, e)
+	if structDef.Readonly[memberName] {
+		panic(fmt.Sprintf("Cannot assign to readonly property %s of struct %s", memberName, target.Name))
 	}
 
-	structVal.Properties[memberName] = value
-
-	env.Variables[varName] = Variable{
-		Value:        structVal,
-		ExpectedType: variable.ExpectedType,
-		Constant:     false,
+	bindings := make(map[string]*TypeSpec)
+	for i, param := range structDef.TypeParams {
+		if i < len(target.TypeArgs) {
+			bindings[param] = target.TypeArgs[i]
+		}
+	}
+	if expectedType = substitute(expectedType, bindings); !checkType(value.Type(), expectedType) {
+		panic(fmt.Sprintf("Cannot assign %s to member %s of type %s in struct %s", value.Type(), memberName, expectedType, target.Name))
 	}
 
+	target.Properties[memberName] = value
 	return structVal
 }
 
+// promotedPath finds the embedded struct providing member, shallower
+// embeddings taking precedence, and returns the chain of embedded properties
+// leading to it.
+func (e *environment) promotedPath(def StructDef, member string) []string {
+	var path []string
+
+	for _, embedded := range def.Embedded {
+		embeddedDef := def.Env.lookupStruct(embedded).(StructDef)
+		_, isProperty := embeddedDef.Properties[member]
+		_, isMethod := embeddedDef.Methods[member]
+		if !isProperty && !isMethod {
+			continue
+		}
+		if path != nil {
+			panic(fmt.Sprintf("Struct %s embeds both %s and %s which define %s", def.Name, path[0], embedded, member))
+		}
+		path = []string{embedded}
+	}
+
+	if path != nil {
+		return path
+	}
+
+	for _, embedded := range def.Embedded {
+		inner := e.promotedPath(def.Env.lookupStruct(embedded).(StructDef), member)
+		if inner == nil {
+			continue
+		}
+		if path != nil {
+			panic(fmt.Sprintf("Struct %s embeds both %s and %s which define %s", def.Name, path[0], embedded, member))
+		}
+		path = append([]string{embedded}, inner...)
+	}
+
+	return path
+}
+
+// embeddedValue follows path from s to the embedded struct it leads to.
+func embeddedValue(s Struct, path []string) Struct {
+	for _, name := range path {
+		inner, ok := s.Properties[name].(Struct)
+		if !ok {
+			panic(fmt.Sprintf("Embedded %s of struct %s is null", name, s.Name))
+		}
+		s = inner
+	}
+	return s
+}
+
+// typeBindings collects the generic type parameters bound in this scope and
+// every enclosing one, nearer scopes taking precedence.
+func (e *environment) typeBindings() map[string]*TypeSpec {
+	bindings := make(map[string]*TypeSpec)
+
+	if e.Parent != nil {
+		bindings = e.Parent.typeBindings()
+	}
+
+	for name, t := range e.Types {
+		bindings[name] = t
+	}
+
+	return bindings
+}
+
+// resolveType converts a type annotation into a *TypeSpec, replacing any
+// type parameters bound in the current scope.
+func (e *environment) resolveType(t ast.Type) *TypeSpec {
+	// bound type parameters shadow aliases of the same name
+	bindings := e.aliases()
+	for name, bound := range e.typeBindings() {
+		bindings[name] = bound
+	}
+	return substitute(extractValueType(t), bindings)
+}
+
+// declareType registers name as an alias of underlying. Distinct types are
+// not aliased, instead a conversion function named after the type wraps
+// values of the underlying type.
+func (e *environment) declareType(name string, underlying *TypeSpec, distinct bool) RuntimeVal {
+	_, isAlias := e.Aliases[name]
+	_, isStruct := e.StructDefs[name]
+	if isAlias || isStruct || e.containsVar(name) {
+		panic(fmt.Sprintf("Type %s has already been declared", name))
+	}
+
+	underlying = e.expandAliases(underlying, nil)
+
+	if !distinct {
+		if e.Aliases == nil {
+			e.Aliases = make(map[string]*TypeSpec)
+		}
+		e.Aliases[name] = underlying
+		return MKNULL()
+	}
+
+	convert := Function{
+		Name:       name,
+		Parameters: []Parameter{{Name: "value", Type: underlying}},
+		ReturnType: namedType(name),
+		NativeFn: NativeFunction{func(args []RuntimeVal) RuntimeVal {
+			if len(args) != 1 {
+				panic(fmt.Sprintf("Conversion to %s expects 1 argument but got %d", name, len(args)))
+			}
+			if !checkType(args[0].Type(), underlying) {
+				panic(fmt.Sprintf("Cannot convert %s to %s, expected %s", args[0].Type(), name, underlying))
+			}
+			return Distinct{TypeName: name, Value: args[0]}
+		}},
+	}
+
+	if e.Distincts == nil {
+		e.Distincts = make(map[string]*TypeSpec)
+	}
+	e.Distincts[name] = underlying
+	return e.declareVar(name, convert, convert.Type(), true)
+}
+
+func (e *environment) lookupAlias(name string) (*TypeSpec, bool) {
+	if t, exists := e.Aliases[name]; exists {
+		return t, true
+	}
+	if e.Parent != nil {
+		return e.Parent.lookupAlias(name)
+	}
+	return nil, false
+}
+
+func (e *environment) lookupDistinct(name string) (*TypeSpec, bool) {
+	if t, exists := e.Distincts[name]; exists {
+		return t, true
+	}
+	if e.Parent != nil {
+		return e.Parent.lookupDistinct(name)
+	}
+	return nil, false
+}
+
+// isMapKeyType reports whether maps may have keys of type t: any, string,
+// number, boolean or a distinct type of one of them.
+func (e *environment) isMapKeyType(t *TypeSpec) bool {
+	if t.Kind != NamedKind || len(t.Args) > 0 {
+		return false
+	}
+	switch t.Name {
+	case AnyType.Name, StringType.Name, NumberType.Name, BooleanType.Name:
+		return true
+	}
+	underlying, distinct := e.lookupDistinct(t.Name)
+	return distinct && e.isMapKeyType(underlying)
+}
+
+// aliases collects the type aliases declared in this scope and every
+// enclosing one, nearer scopes taking precedence.
+func (e *environment) aliases() map[string]*TypeSpec {
+	aliases := make(map[string]*TypeSpec)
+
+	if e.Parent != nil {
+		aliases = e.Parent.aliases()
+	}
+
+	for name, t := range e.Aliases {
+		aliases[name] = t
+	}
+
+	return aliases
+}
+
+// expandAliases replaces every alias named inside t, including nested ones
+// such as array<UserId>, with the type it stands for. Type parameters shadow
+// aliases of the same name and are left as they are.
+func (e *environment) expandAliases(t *TypeSpec, typeParams []string) *TypeSpec {
+	aliases := e.aliases()
+	for _, param := range typeParams {
+		delete(aliases, param)
+	}
+	return substitute(t, aliases)
+}
+
+// resolveParameters expands aliases used in parameter types.
+func (e *environment) resolveParameters(params []Parameter, typeParams []string) []Parameter {
+	for i := range params {
+		params[i].Type = e.expandAliases(params[i].Type, typeParams)
+	}
+	return params
+}
+
+// isStrict reports whether code declared in this scope runs with null
+// safety, either through the file's "use strict"; directive or SetStrict.
+func (e *environment) isStrict() bool {
+	if e.Strict {
+		return true
+	}
+	if e.Parent != nil {
+		return e.Parent.isStrict()
+	}
+	return strictByDefault
+}
+
+func (e *environment) declares(name string) bool {
+	_, isStruct := e.StructDefs[name]
+	_, isFn := e.Functions[name]
+	_, isAlias := e.Aliases[name]
+	return isStruct || isFn || isAlias || e.containsVar(name)
+}
+
+// module returns the scope of the file e belongs to.
+func (e *environment) module() *environment {
+	if e.Parent != nil {
+		return e.Parent.module()
+	}
+	return e
+}
+
+func (e *environment) export(name string) {
+	if e.Parent != nil {
+		panic(fmt.Sprintf("Cannot export %s, only top level declarations can be exported", name))
+	}
+	e.Exports[name] = true
+}
+
 func (e *environment) declareNativeFn(fnName string, call FunctionCall) {
 	e.Functions[fnName] = Function{Name: fnName, NativeFn: NativeFunction{call}}
 }
 
-func (e *environment) addImport(importedEnv *environment, modules []string) *environment {
+func (e *environment) addImport(importedEnv *environment, modules []string, path string) *environment {
 
 	for _, name := range modules {
+		if !importedEnv.Exports[name] {
+			if importedEnv.declares(name) {
+				panic(fmt.Sprintf("Cannot import %s from %s, it is not exported", name, path))
+			}
+			panic(fmt.Sprintf("Cannot import %s from %s, no such declaration", name, path))
+		}
+
 		if structDef, exists := importedEnv.StructDefs[name]; exists {
 			e.StructDefs[name] = structDef
 		}
@@ -193,6 +490,14 @@ func (e *environment) addImport(importedEnv *environment, modules []string) *env
 		if variable, exists := importedEnv.Variables[name]; exists {
 			e.Variables[name] = variable
 		}
+
+		if alias, exists := importedEnv.Aliases[name]; exists {
+			e.Aliases[name] = alias
+		}
+
+		if underlying, exists := importedEnv.Distincts[name]; exists {
+			e.Distincts[name] = underlying
+		}
 	}
 
 	return e
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"fmt"
+	"math"
 	"shiplang/src/ast"
 	"shiplang/src/lexer"
 )
@@ -21,8 +22,16 @@ func eval_expr(expr ast.Expr, env *environment) RuntimeVal {
 		return eval_binary_expr(e, env)
 	case ast.ArrayInstantiationExpr:
 		return eval_array_inst_expr(e, env)
+	case ast.MapInstantiationExpr:
+		return eval_map_inst_expr(e, env)
+	case ast.SetInstantiationExpr:
+		return eval_set_inst_expr(e, env)
+	case ast.TupleExpr:
+		return eval_tuple_expr(e, env)
 	case ast.ArrayAccessExpr:
 		return eval_array_access_expr(e, env)
+	case ast.SliceExpr:
+		return eval_slice_expr(e, env)
 	case ast.StructInstantiationExpr:
 		return eval_struct_inst_expr(e, env)
 	case ast.CallExpr:
@@ -31,6 +40,8 @@ func eval_expr(expr ast.Expr, env *environment) RuntimeVal {
 		return eval_member_access_expr(e, env)
 	case ast.AssignmentExpr:
 		return eval_assignment_expr(e, env)
+	case ast.MatchExpr:
+		return eval_match_expr(e, env)
 
 	default:
 		panic(fmt.Sprintf("expr not set up %s", e))
@@ -50,21 +61,63 @@ func eval_prefix_expr(pr ast.PrefixExpr, env *environment) RuntimeVal {
 		return negate(right)
 	case lexer.PLUS:
 		return right
+	case lexer.TILDE:
+		n, ok := right.(Number)
+		if !ok {
+			panic(fmt.Sprintf("Operator ~ cannot be applied to %s", right.Type()))
+		}
+		return MKNUM(float64(^toInteger(n, pr.Operator)))
+	case lexer.TYPEOF:
+		return MKSTR(right.Type().String())
 	default:
 		panic("Unknown prefix operator")
 	}
 }
 
+// eval_binary_expr applies a binary operator. Operands are never converted,
+// the operators accept:
+//
+//	&& ||          any values, by truthiness, the right operand is only
+//	               evaluated when the left one does not decide the result
+//	== !=          any values, compared structurally
+//	+              two numbers, two strings (concatenation) or two arrays
+//	               (concatenation, the element types must be compatible)
+//	- * / % ** div two numbers, div divides rounding down and % keeps the
+//	               sign of the left operand
+//	& | ^ << >>    two whole numbers
+//	< <= > >=      two numbers, or two strings compared lexicographically
+//
+// Every other combination is an error.
 func eval_binary_expr(b ast.BinaryExpr, env *environment) RuntimeVal {
-	lhs := eval_expr(b.Left, env)
-	rhs := eval_expr(b.Right, env)
-	lhsType, rhsType := lhs.Type(), rhs.Type()
+	switch b.Operator.Kind {
+	case lexer.AND:
+		return MKBOOL(truthify(eval_expr(b.Left, env)) && truthify(eval_expr(b.Right, env)))
+	case lexer.OR:
+		return MKBOOL(truthify(eval_expr(b.Left, env)) || truthify(eval_expr(b.Right, env)))
+	}
 
-	if lhsType == NumberType && rhsType == NumberType {
-		return eval_numeric_binary_expr(lhs.(Number), rhs.(Number), b.Operator)
+	return eval_binary_values(eval_expr(b.Left, env), eval_expr(b.Right, env), b.Operator)
+}
+
+// eval_binary_values applies a binary operator other than && and || to
+// evaluated operands.
+func eval_binary_values(lhs RuntimeVal, rhs RuntimeVal, op lexer.Token) RuntimeVal {
+	switch lhs := lhs.(type) {
+	case Number:
+		if rhs, ok := rhs.(Number); ok {
+			return eval_numeric_binary_expr(lhs, rhs, op)
+		}
+	case String:
+		if rhs, ok := rhs.(String); ok {
+			return eval_string_binary_expr(lhs, rhs, op)
+		}
+	case Array:
+		if rhs, ok := rhs.(Array); ok && op.Kind == lexer.PLUS {
+			return concatArrays(lhs, rhs)
+		}
 	}
 
-	return eval_logical_binary_expr(lhs, rhs, b.Operator)
+	return eval_equality_binary_expr(lhs, rhs, op)
 }
 
 func eval_numeric_binary_expr(lhs Number, rhs Number, op lexer.Token) RuntimeVal {
@@ -86,7 +139,16 @@ func eval_numeric_binary_expr(lhs Number, rhs Number, op lexer.Token) RuntimeVal
 		if rhs.Value == 0 {
 			panic("Division by zero")
 		}
-		res = float64(int(lhs.Value) % int(rhs.Value))
+		res = math.Mod(lhs.Value, rhs.Value)
+	case lexer.DIV:
+		if rhs.Value == 0 {
+			panic("Division by zero")
+		}
+		res = math.Floor(lhs.Value / rhs.Value)
+	case lexer.STAR_STAR:
+		res = math.Pow(lhs.Value, rhs.Value)
+	case lexer.AMPERSAND, lexer.PIPE, lexer.CARET, lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT:
+		return eval_bitwise_binary_expr(lhs, rhs, op)
 	default:
 		return eval_comparison_binary_expr(lhs, rhs, op)
 	}
@@ -94,6 +156,40 @@ func eval_numeric_binary_expr(lhs Number, rhs Number, op lexer.Token) RuntimeVal
 	return MKNUM(res)
 }
 
+func eval_bitwise_binary_expr(lhs Number, rhs Number, op lexer.Token) RuntimeVal {
+	l, r := toInteger(lhs, op), toInteger(rhs, op)
+	var res int64
+
+	switch op.Kind {
+	case lexer.AMPERSAND:
+		res = l & r
+	case lexer.PIPE:
+		res = l | r
+	case lexer.CARET:
+		res = l ^ r
+	default:
+		if r < 0 {
+			panic(fmt.Sprintf("Shift count %d cannot be negative", r))
+		}
+		if op.Kind == lexer.SHIFT_LEFT {
+			res = l << r
+		} else {
+			res = l >> r
+		}
+	}
+
+	return MKNUM(float64(res))
+}
+
+// toInteger converts an operand of a bitwise operator, those only accept
+// whole numbers
+func toInteger(n Number, op lexer.Token) int64 {
+	if n.Value != math.Trunc(n.Value) || math.IsInf(n.Value, 0) {
+		panic(fmt.Sprintf("Operator %s requires whole numbers, got %s", op.Value, n.Inspect()))
+	}
+	return int64(n.Value)
+}
+
 func eval_comparison_binary_expr(lhs Number, rhs Number, op lexer.Token) RuntimeVal {
 	var res bool
 
@@ -106,39 +202,61 @@ func eval_comparison_binary_expr(lhs Number, rhs Number, op lexer.Token) Runtime
 		res = lhs.Value < rhs.Value
 	case lexer.LESS_EQUALS:
 		res = lhs.Value <= rhs.Value
-	case lexer.EQUALS:
-		res = equals(lhs, rhs)
-	case lexer.NOT_EQUALS:
-		res = !equals(lhs, rhs)
 	default:
-		panic("Unknown comparison operator")
+		return eval_equality_binary_expr(lhs, rhs, op)
 	}
 
 	return MKBOOL(res)
 }
 
-func eval_logical_binary_expr(lhs RuntimeVal, rhs RuntimeVal, op lexer.Token) RuntimeVal {
-	var res bool
+func eval_string_binary_expr(lhs String, rhs String, op lexer.Token) RuntimeVal {
+	switch op.Kind {
+	case lexer.PLUS:
+		return MKSTR(lhs.Value + rhs.Value)
+	case lexer.LESS:
+		return MKBOOL(lhs.Value < rhs.Value)
+	case lexer.LESS_EQUALS:
+		return MKBOOL(lhs.Value <= rhs.Value)
+	case lexer.GREATER:
+		return MKBOOL(lhs.Value > rhs.Value)
+	case lexer.GREATER_EQUALS:
+		return MKBOOL(lhs.Value >= rhs.Value)
+	default:
+		return eval_equality_binary_expr(lhs, rhs, op)
+	}
+}
+
+// concatArrays joins two arrays into a new one whose element type accepts
+// the elements of both.
+func concatArrays(lhs Array, rhs Array) RuntimeVal {
+	elementType := lhs.ElementType
+	if !checkType(rhs.ElementType, elementType) {
+		if !checkType(lhs.ElementType, rhs.ElementType) {
+			panic(fmt.Sprintf("Operator + cannot be applied to %s and %s", lhs.Type(), rhs.Type()))
+		}
+		elementType = rhs.ElementType
+	}
+
+	elements := make([]RuntimeVal, 0, len(lhs.Elements)+len(rhs.Elements))
+	elements = append(elements, lhs.Elements...)
+	elements = append(elements, rhs.Elements...)
+	return Array{Elements: elements, ElementType: elementType}
+}
 
+func eval_equality_binary_expr(lhs RuntimeVal, rhs RuntimeVal, op lexer.Token) RuntimeVal {
 	switch op.Kind {
-	case lexer.AND:
-		res = truthify(lhs) && truthify(rhs)
-	case lexer.OR:
-		res = truthify(lhs) || truthify(rhs)
 	case lexer.EQUALS:
-		res = equals(lhs, rhs)
+		return MKBOOL(equals(lhs, rhs))
 	case lexer.NOT_EQUALS:
-		res = !equals(rhs, lhs)
+		return MKBOOL(!equals(lhs, rhs))
 	default:
-		panic("Unknown logical operator")
+		panic(fmt.Sprintf("Operator %s cannot be applied to %s and %s", op.Value, lhs.Type(), rhs.Type()))
 	}
-
-	return MKBOOL(res)
 }
 
 func eval_array_inst_expr(ai ast.ArrayInstantiationExpr, env *environment) RuntimeVal {
 	elements := make([]RuntimeVal, 0, len(ai.Contents))
-	elementType := extractValueType(ai.Underlying)
+	elementType := env.resolveType(ai.Underlying)
 
 	for _, element := range ai.Contents {
 		val := eval_expr(element, env)
@@ -151,43 +269,189 @@ func eval_array_inst_expr(ai ast.ArrayInstantiationExpr, env *environment) Runti
 	return Array{Elements: elements, ElementType: elementType}
 }
 
+func eval_map_inst_expr(mi ast.MapInstantiationExpr, env *environment) RuntimeVal {
+	keyType := env.resolveType(mi.KeyType)
+	if !env.isMapKeyType(keyType) {
+		panic(fmt.Sprintf("Unsupported map key type %s", keyType))
+	}
+
+	m := NewMap(keyType, env.resolveType(mi.ValueType))
+
+	for _, entry := range mi.Entries {
+		m.Set(eval_expr(entry.Key, env), eval_expr(entry.Value, env))
+	}
+
+	return m
+}
+
+func eval_set_inst_expr(si ast.SetInstantiationExpr, env *environment) RuntimeVal {
+	s := NewSet(env.resolveType(si.Underlying))
+
+	for _, element := range si.Contents {
+		s.Add(eval_expr(element, env))
+	}
+
+	return s
+}
+
+func eval_tuple_expr(t ast.TupleExpr, env *environment) RuntimeVal {
+	elements := make([]RuntimeVal, len(t.Elements))
+
+	for i, element := range t.Elements {
+		elements[i] = eval_expr(element, env)
+	}
+
+	return Tuple{Elements: elements}
+}
+
 func eval_array_access_expr(aa ast.ArrayAccessExpr, env *environment) RuntimeVal {
-	a := eval_expr(aa.Array, env)
-	i := eval_expr(aa.Index, env)
+	return indexValue(eval_expr(aa.Array, env), eval_expr(aa.Index, env))
+}
+
+// indexValue reads the element of an evaluated container at an evaluated
+// index or key.
+func indexValue(a RuntimeVal, i RuntimeVal) RuntimeVal {
+	if m, ok := a.(*Map); ok {
+		value, exists := m.Get(i)
+		if !exists {
+			panic(fmt.Sprintf("Key %s not found in map", i.Inspect()))
+		}
+		return value
+	}
 
-	if i.Type() != NumberType {
+	if !i.Type().Equal(NumberType) {
 		panic("Array index must be a number")
 	}
 
 	index := int(i.(Number).Value)
 
 	switch a := a.(type) {
+	case Tuple:
+		if index < 0 || index >= len(a.Elements) {
+			panic(fmt.Sprintf("Tuple index out of range: %d", index))
+		}
+		return a.Elements[index]
 	case String:
-		return eval_string_access_expr(a, index, aa.Rest, aa.Prev)
+		return MKSTR(string(a.Value[resolveIndex(index, len(a.Value), "string")]))
 	case Array:
-		if aa.Prev {
-			return Array{Elements: a.Elements[:index], ElementType: a.ElementType}
+		return a.Elements[resolveIndex(index, len(a.Elements), "array")]
+	default:
+		panic("Invalid array access")
+	}
+}
+
+func eval_slice_expr(sl ast.SliceExpr, env *environment) RuntimeVal {
+	return sliceValue(eval_expr(sl.Array, env), eval_slice_bounds(sl, env))
+}
+
+// sliceValue takes the slice of an evaluated string or array.
+func sliceValue(sliced RuntimeVal, bounds sliceBounds) RuntimeVal {
+	switch a := sliced.(type) {
+	case String:
+		indices := sliceIndices(bounds.resolve(len(a.Value)))
+		result := make([]byte, len(indices))
+		for i, index := range indices {
+			result[i] = a.Value[index]
 		}
-		if aa.Rest {
-			return Array{Elements: a.Elements[index:], ElementType: a.ElementType}
+		return MKSTR(string(result))
+	case Array:
+		indices := sliceIndices(bounds.resolve(len(a.Elements)))
+		elements := make([]RuntimeVal, len(indices))
+		for i, index := range indices {
+			elements[i] = a.Elements[index]
 		}
-		return a.Elements[index]
+		return Array{Elements: elements, ElementType: a.ElementType}
 	default:
-		panic("Invalid array access")
+		panic(fmt.Sprintf("Cannot slice %s", a.Type()))
+	}
+}
+
+// sliceBounds holds the evaluated bounds of a slice, nil for the ones left
+// out.
+type sliceBounds struct {
+	start, end, step *int
+}
+
+func eval_slice_bounds(sl ast.SliceExpr, env *environment) sliceBounds {
+	bound := func(expr ast.Expr, name string) *int {
+		if expr == nil {
+			return nil
+		}
+		n, ok := eval_expr(expr, env).(Number)
+		if !ok {
+			panic(fmt.Sprintf("Slice %s must be a number", name))
+		}
+		value := int(n.Value)
+		return &value
+	}
+
+	return sliceBounds{start: bound(sl.Start, "start"), end: bound(sl.End, "end"), step: bound(sl.Step, "step")}
+}
+
+// resolve places the bounds in a sequence of length elements, filling in
+// the defaults of the bounds left out.
+func (b sliceBounds) resolve(length int) (start int, end int, step int) {
+	step = 1
+	if b.step != nil {
+		step = *b.step
+	}
+	if step == 0 {
+		panic("Slice step cannot be zero")
+	}
+
+	// a negative step walks backwards, from the last element by default
+	start, end = 0, length
+	if step < 0 {
+		start, end = length-1, -1
+	}
+
+	if b.start != nil {
+		start = resolveBound(*b.start, length, "start")
+		if step < 0 && start == length {
+			start = length - 1
+		}
+	}
+	if b.end != nil {
+		end = resolveBound(*b.end, length, "end")
+	}
+
+	return start, end, step
+}
+
+// sliceIndices lists the indices selected by resolved slice bounds, in the
+// order the slice visits them.
+func sliceIndices(start int, end int, step int) []int {
+	var indices []int
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		indices = append(indices, i)
 	}
+	return indices
 }
 
-func eval_string_access_expr(s String, index int, rest bool, prev bool) RuntimeVal {
-	if index < 0 || index >= len(s.Value) {
-		panic("String index out of bounds")
+// resolveIndex turns a possibly negative index into a position inside a
+// sequence of the given length, counting negative ones from the end.
+func resolveIndex(index int, length int, kind string) int {
+	resolved := index
+	if resolved < 0 {
+		resolved += length
+	}
+	if resolved < 0 || resolved >= length {
+		panic(fmt.Sprintf("Index %d out of range for %s of length %d", index, kind, length))
 	}
-	if rest {
-		return MKSTR(s.Value[:index])
+	return resolved
+}
+
+// resolveBound is resolveIndex for slice bounds, which may also point just
+// past the last element.
+func resolveBound(bound int, length int, name string) int {
+	resolved := bound
+	if resolved < 0 {
+		resolved += length
 	}
-	if prev {
-		return MKSTR(s.Value[index:])
+	if resolved < 0 || resolved > length {
+		panic(fmt.Sprintf("Slice %s %d out of range for length %d", name, bound, length))
 	}
-	return MKSTR(string(s.Value[index]))
+	return resolved
 }
 
 func eval_struct_inst_expr(si ast.StructInstantiationExpr, env *environment) RuntimeVal {
@@ -197,135 +461,360 @@ func eval_struct_inst_expr(si ast.StructInstantiationExpr, env *environment) Run
 		panic(fmt.Sprintf("Struct %s not found", si.StructName))
 	}
 
+	for _, name := range si.Order {
+		if _, exists := structDef.Properties[name]; !exists {
+			panic(fmt.Sprintf("Struct %s has no property %s", si.StructName, name))
+		}
+		checkVisible(structDef, name, env)
+	}
+
+	// given values are evaluated in the order they are written, defaults
+	// and checks follow the declaration order
+	given := make(map[string]RuntimeVal, len(si.Order))
+	for _, name := range si.Order {
+		given[name] = eval_expr(si.Properties[name], env)
+	}
+
 	evalProps := make(map[string]RuntimeVal, len(structDef.Properties))
+	bindings := make(map[string]*TypeSpec)
+
+	for _, name := range structDef.Order {
+		expectedType := structDef.Properties[name]
+		propVal, ok := given[name]
 
-	for name, expectedType := range structDef.Properties {
-		if prop, ok := si.Properties[name]; ok {
-			propVal := eval_expr(prop, env)
-			if !checkType(propVal.Type(), expectedType) {
-				panic(fmt.Sprintf("Type mismatch for property %s in struct %s", name, si.StructName))
+		if !ok {
+			if defaultValue, hasDefault := structDef.Defaults[name]; hasDefault {
+				propVal = eval_expr(defaultValue, structDef.Env)
+			} else if structDef.Required[name] {
+				panic(fmt.Sprintf("Missing required property %s in struct %s", name, si.StructName))
+			} else {
+				if !checkType(NullType, expectedType) {
+					panic(fmt.Sprintf("Missing property %s of non-nullable type %s in struct %s", name, expectedType, si.StructName))
+				}
+				evalProps[name] = MKNULL()
+				continue
 			}
-			evalProps[name] = propVal
-		} else {
-			evalProps[name] = MKNULL()
+		}
+
+		if !unify(expectedType, propVal.Type(), structDef.TypeParams, bindings) {
+			panic(fmt.Sprintf("Type mismatch for property %s in struct %s: expected %s got %s", name, si.StructName, substitute(expectedType, bindings), propVal.Type()))
+		}
+		evalProps[name] = propVal
+	}
+
+	var typeArgs []*TypeSpec
+	if len(structDef.TypeParams) > 0 {
+		bindTypeParams(structDef.TypeParams, bindings)
+		for _, param := range structDef.TypeParams {
+			typeArgs = append(typeArgs, bindings[param])
 		}
 	}
 
-	return Struct{
+	instance := Struct{
 		Name:       si.StructName,
+		TypeArgs:   typeArgs,
 		Properties: evalProps,
+		Order:      structDef.Order,
+		Def:        structDef,
 	}
+
+	// validate(self) runs after every instantiation, returning false or an
+	// error message rejects the instance
+	if validate, exists := structDef.Methods["validate"]; exists {
+		switch result := invoke_function(validate, []RuntimeVal{instance}, nil, env).(type) {
+		case Bool:
+			if !result.Value {
+				panic(fmt.Sprintf("Validation of %s failed", si.StructName))
+			}
+		case String:
+			panic(fmt.Sprintf("Validation of %s failed: %s", si.StructName, result.Value))
+		}
+	}
+
+	return instance
 }
 
 func eval_call_expr(c ast.CallExpr, env *environment) RuntimeVal {
+	if c.Callee != nil {
+		fn, ok := eval_expr(c.Callee, env).(Function)
+		if !ok {
+			panic("Called expression is not a function")
+		}
+		return call_function(fn, c.Arguments, env)
+	}
+
 	if c.Struct != nil {
 		return handle_method_call(c, env)
 	}
 
-	v := env.containsVar(c.FunctionName)
-	if v {
-		// Check if the variable value is a function reference
-		v := env.Variables[c.FunctionName]
-		if fnRef, ok := v.Value.(Function); ok {
-			// Evaluate arguments
-			callEnv := &environment{Variables: make(map[string]Variable), Parent: env}
-			for i, param := range fnRef.Parameters {
+	return call_function(env.lookupCallable(c.FunctionName), c.Arguments, env)
+}
+
+type namedArgument struct {
+	Name  string
+	Value RuntimeVal
+}
 
-				callEnv.declareVar(param.Name, eval_expr(c.Arguments[i], callEnv), param.Type, false)
+// eval_call_args evaluates call arguments in the caller's environment,
+// expanding spread arguments into positional ones.
+func eval_call_args(argExprs []ast.Expr, env *environment) ([]RuntimeVal, []namedArgument) {
+	positional := make([]RuntimeVal, 0, len(argExprs))
+	named := make([]namedArgument, 0)
+
+	for _, arg := range argExprs {
+		switch a := arg.(type) {
+		case ast.NamedArgumentExpr:
+			named = append(named, namedArgument{Name: a.Name, Value: eval_expr(a.Value, env)})
+		case ast.SpreadExpr:
+			switch v := eval_expr(a.Value, env).(type) {
+			case Array:
+				positional = append(positional, v.Elements...)
+			case Tuple:
+				positional = append(positional, v.Elements...)
+			default:
+				panic(fmt.Sprintf("Cannot spread %s into call arguments", v.Type()))
 			}
-			// Call the referenced function
-			return Evaluate(fnRef.Body, callEnv)
-		} else {
-			// Handle the case where the variable is not a function reference
-			panic(fmt.Sprintf("%s is not a function reference", c.FunctionName))
+		default:
+			positional = append(positional, eval_expr(arg, env))
 		}
 	}
 
-	fnVal, ok := env.lookupFn(c.FunctionName).(Function)
+	return positional, named
+}
+
+// eval_positional_args is used by callees without parameter names such as
+// native functions and builtin methods.
+func eval_positional_args(calleeName string, argExprs []ast.Expr, env *environment) []RuntimeVal {
+	positional, named := eval_call_args(argExprs, env)
 
-	if !ok {
-		panic(fmt.Sprintf("%s is not callable", c.FunctionName))
+	if len(named) > 0 {
+		panic(fmt.Sprintf("%s does not accept named arguments", calleeName))
 	}
 
-	if fnVal.NativeFn.Call != nil {
+	return positional
+}
+
+func call_function(fn Function, argExprs []ast.Expr, env *environment) RuntimeVal {
+	if fn.NativeFn.Call != nil {
+		return fn.NativeFn.Call(eval_positional_args(fn.Name, argExprs, env))
+	}
+
+	positional, named := eval_call_args(argExprs, env)
+	return invoke_function(fn, positional, named, env)
+}
+
+// invoke_function calls fn with arguments that are already evaluated.
+func invoke_function(fn Function, positional []RuntimeVal, named []namedArgument, env *environment) RuntimeVal {
+	if fn.NativeFn.Call != nil {
+		return fn.NativeFn.Call(positional)
+	}
 
-		var evalArgs []RuntimeVal
-		for _, arg := range c.Arguments {
-			evalArgs = append(evalArgs, eval_expr(arg, env))
+	// functions of another file see the declarations of that file, its
+	// private ones included, instead of those of the caller
+	parent := env
+	if fn.Env != nil && fn.Env.module() != env.module() {
+		parent = fn.Env
+	}
+	callEnv := &environment{Variables: make(map[string]Variable), Parent: parent, Call: true}
+
+	// the body runs in the null safety mode of the file declaring it
+	previous, previousModule := strictNulls, currentModule
+	if fn.Env != nil {
+		strictNulls = fn.Env.isStrict()
+		currentModule = fn.Env.module()
+	}
+	defer func() { strictNulls, currentModule = previous, previousModule }()
+
+	bind_arguments(fn, positional, named, callEnv)
+
+	return eval_fn_body(fn, callEnv)
+}
+
+// bind_arguments declares every parameter of fn inside callEnv, matching
+// positional arguments first, then named ones, then defaults. Defaults are
+// evaluated in callEnv so they can refer to earlier parameters.
+func bind_arguments(fn Function, positional []RuntimeVal, named []namedArgument, callEnv *environment) {
+	values := make([]RuntimeVal, len(fn.Parameters))
+	bindings := make(map[string]*TypeSpec)
+	var variadic []RuntimeVal
+
+	for i, arg := range positional {
+		if i < len(fn.Parameters) && !fn.Parameters[i].Variadic {
+			values[i] = arg
+		} else if len(fn.Parameters) > 0 && fn.Parameters[len(fn.Parameters)-1].Variadic {
+			variadic = append(variadic, arg)
+		} else {
+			panic(fmt.Sprintf("Too many arguments for function %s: expected at most %d, got %d", fn.Name, len(fn.Parameters), len(positional)))
 		}
-		return fnVal.NativeFn.Call(evalArgs)
 	}
 
-	argCount := len(c.Arguments)
-	paramCount := len(fnVal.Parameters)
+	for _, arg := range named {
+		index := -1
+		for i, param := range fn.Parameters {
+			if param.Name == arg.Name {
+				index = i
+			}
+		}
+
+		if index == -1 {
+			panic(fmt.Sprintf("Function %s has no parameter named %s", fn.Name, arg.Name))
+		}
+		if fn.Parameters[index].Variadic {
+			panic(fmt.Sprintf("Variadic parameter %s of function %s cannot be passed by name", arg.Name, fn.Name))
+		}
+		if values[index] != nil {
+			panic(fmt.Sprintf("Argument %s of function %s given more than once", arg.Name, fn.Name))
+		}
 
-	if argCount != paramCount {
-		panic(fmt.Sprintf("Incorrect number of arguments for function %s: expected %d, got %d", c.FunctionName, paramCount, argCount))
+		values[index] = arg.Value
 	}
 
-	callEnv := &environment{Variables: make(map[string]Variable), Parent: env}
+	// generic functions expose their type parameters to the body, bound
+	// from the arguments as they are checked
+	callEnv.Types = bindings
 
-	for i, param := range fnVal.Parameters {
+	for i, param := range fn.Parameters {
+		if param.Variadic {
+			for _, arg := range variadic {
+				if !unify(param.Type, arg.Type(), fn.TypeParams, bindings) {
+					panic(fmt.Sprintf("Argument %s of function %s expected %s got %s", param.Name, fn.Name, substitute(param.Type, bindings), arg.Type()))
+				}
+			}
+			rest := Array{Elements: variadic, ElementType: substitute(param.Type, bindings)}
+			callEnv.declareVar(param.Name, rest, rest.Type(), false)
+			continue
+		}
 
-		callEnv.declareVar(param.Name, eval_expr(c.Arguments[i], callEnv), param.Type, false)
+		val := values[i]
+		if val == nil {
+			if param.Default == nil {
+				panic(fmt.Sprintf("Missing argument %s for function %s", param.Name, fn.Name))
+			}
+			val = eval_expr(param.Default, callEnv)
+		}
+
+		if !unify(param.Type, val.Type(), fn.TypeParams, bindings) {
+			panic(fmt.Sprintf("Argument %s of function %s expected %s got %s", param.Name, fn.Name, substitute(param.Type, bindings), val.Type()))
+		}
+
+		callEnv.declareVar(param.Name, val, substitute(param.Type, bindings), false)
+	}
+
+	bindTypeParams(fn.TypeParams, bindings)
+}
+
+// eval_fn_body runs a user defined function inside callEnv and checks the
+// produced value against the declared return type.
+func eval_fn_body(fn Function, callEnv *environment) RuntimeVal {
+	result := Evaluate(fn.Body, callEnv)
+	site := "the end of its body"
+
+	if ret, ok := result.(Return); ok {
+		result = ret.Value
+		site = fmt.Sprintf("return at line %d", ret.Line)
 	}
 
-	return Evaluate(fnVal.Body, callEnv)
+	returnType := substitute(fn.ReturnType, callEnv.Types)
+	if !checkType(result.Type(), returnType) {
+		panic(fmt.Sprintf("Function %s must return %s but %s produced %s", fn.Name, returnType, site, result.Type()))
+	}
 
+	return result
 }
 
 func handle_method_call(c ast.CallExpr, env *environment) RuntimeVal {
 	v := eval_expr(c.Struct, env)
-	structType := string(v.Type())
+
+	// methods of distinct types are those of the underlying value
+	if distinct, ok := v.(Distinct); ok {
+		v = distinct.Value
+	}
+
+	structType := v.Type().String()
+
+	structVal, isStruct := v.(Struct)
+	if isStruct {
+		structType = structVal.Name
+	}
+
+	if enumDef, ok := v.(EnumDef); ok {
+		return enumDef.construct(c.FunctionName, eval_positional_args(c.FunctionName, c.Arguments, env))
+	}
 
 	if isPrimitive(v.Type()) {
 		return handle_primitive_method_call(v, c, env)
 	}
 
-	structDef, ok := env.lookupStruct(structType).(StructDef)
-	if !ok {
-		panic(fmt.Sprintf("Struct %s not found", structType))
+	structDef := structVal.Def
+	if !isStruct {
+		var ok bool
+		if structDef, ok = env.lookupStruct(structType).(StructDef); !ok {
+			panic(fmt.Sprintf("Struct %s not found", structType))
+		}
 	}
 
 	function, exists := structDef.Methods[c.FunctionName]
 	if !exists {
+		// properties holding functions are called like methods
+		if fn, ok := structVal.Properties[c.FunctionName].(Function); ok {
+			return call_function(fn, c.Arguments, env)
+		}
+		if path := env.promotedPath(structDef, c.FunctionName); path != nil {
+			return call_promoted_method(structVal, path, c, env)
+		}
 		panic(fmt.Sprintf("Method %s not found in struct %s", c.FunctionName, structType))
 	}
 
-	callEnv := &environment{Variables: make(map[string]Variable), Parent: env}
+	return call_function(function, c.Arguments, env)
+}
+
+// call_promoted_method calls a method of a struct embedded in outer. Like in
+// Go the method receives the embedded value, so passing outer as self hands
+// it the embedded struct instead.
+func call_promoted_method(outer Struct, path []string, c ast.CallExpr, env *environment) RuntimeVal {
+	inner := embeddedValue(outer, path)
+	innerDef := inner.Def
 
-	for i, param := range function.Parameters {
-		callEnv.declareVar(param.Name, eval_expr(c.Arguments[i], callEnv), param.Type, false)
+	if _, isProperty := innerDef.Properties[c.FunctionName]; isProperty {
+		fn, ok := inner.Properties[c.FunctionName].(Function)
+		if !ok {
+			panic(fmt.Sprintf("Method %s not found in struct %s", c.FunctionName, outer.Name))
+		}
+		return call_function(fn, c.Arguments, env)
+	}
+
+	positional, named := eval_call_args(c.Arguments, env)
+	if len(positional) > 0 {
+		if self, ok := positional[0].(Struct); ok && self.Name == outer.Name {
+			positional[0] = embeddedValue(self, path)
+		}
 	}
 
-	return Evaluate(function.Body, callEnv)
+	return invoke_function(innerDef.Methods[c.FunctionName], positional, named, env)
 }
 
+// mutatingMethods are the builtin methods that change their receiver.
+var mutatingMethods = map[string]bool{"set": true, "delete": true, "add": true, "remove": true}
+
 func handle_primitive_method_call(v RuntimeVal, c ast.CallExpr, env *environment) RuntimeVal {
+	args := eval_positional_args(c.FunctionName, c.Arguments, env)
+
+	if mutatingMethods[c.FunctionName] {
+		checkMutable(v, GetRootVariableName(c.Struct), env)
+	}
+
 	switch v := v.(type) {
 	case String:
-		args := make([]RuntimeVal, len(c.Arguments))
-		for i, arg := range c.Arguments {
-			args[i] = eval_expr(arg, env)
-		}
 		return v.CallMethod(c.FunctionName, args...)
 	case Number:
-		args := make([]RuntimeVal, len(c.Arguments))
-		for i, arg := range c.Arguments {
-			args[i] = eval_expr(arg, env)
-		}
 		return v.CallMethod(c.FunctionName, args...)
 	case Array:
-		args := make([]RuntimeVal, len(c.Arguments))
-		for i, arg := range c.Arguments {
-			args[i] = eval_expr(arg, env)
-		}
 		return v.CallMethod(c.FunctionName, args...)
 	case Bool:
-		args := make([]RuntimeVal, len(c.Arguments))
-		for i, arg := range c.Arguments {
-			args[i] = eval_expr(arg, env)
-		}
+		return v.CallMethod(c.FunctionName, args...)
+	case *Map:
+		return v.CallMethod(c.FunctionName, args...)
+	case *Set:
 		return v.CallMethod(c.FunctionName, args...)
 	default:
 		return MKNULL()
@@ -333,8 +822,26 @@ func handle_primitive_method_call(v RuntimeVal, c ast.CallExpr, env *environment
 }
 
 func eval_member_access_expr(ma ast.MemberAccessExpr, env *environment) RuntimeVal {
+	return memberValue(eval_expr(ma.Struct, env), ma, env)
+}
 
-	structVal := eval_expr(ma.Struct, env)
+// memberValue reads the member named by ma from its evaluated container.
+func memberValue(structVal RuntimeVal, ma ast.MemberAccessExpr, env *environment) RuntimeVal {
+	switch v := structVal.(type) {
+	case EnumDef:
+		return v.construct(ma.Member, []RuntimeVal{})
+	case Enum:
+		fieldVal, exists := v.field(ma.Member)
+		if !exists {
+			panic(fmt.Sprintf("Field %s not found in %s.%s", ma.Member, v.Def.Name, v.Variant))
+		}
+		return fieldVal
+	case Distinct:
+		if ma.Member != "value" {
+			panic(fmt.Sprintf("Member %s not found in %s, use .value to get the underlying %s", ma.Member, v.TypeName, v.Value.Type()))
+		}
+		return v.Value
+	}
 
 	structInstance, ok := structVal.(Struct)
 
@@ -342,45 +849,202 @@ func eval_member_access_expr(ma ast.MemberAccessExpr, env *environment) RuntimeV
 		panic(fmt.Sprintf("%v is not a struct instance", ma.Struct))
 	}
 
+	structDef := structInstance.Def
+
 	memberVal, exists := structInstance.Properties[ma.Member]
-	if !exists {
-		panic(fmt.Sprintf("Member %s not found in struct %s", ma.Member, structInstance.Name))
+	if exists {
+		checkVisible(structDef, ma.Member, env)
+		return memberVal
 	}
 
-	return memberVal
+	if path := env.promotedPath(structDef, ma.Member); path != nil {
+		owner := embeddedValue(structInstance, path)
+		checkVisible(owner.Def, ma.Member, env)
+		return owner.Properties[ma.Member]
+	}
+
+	panic(fmt.Sprintf("Member %s not found in struct %s", ma.Member, structInstance.Name))
 }
 
 func eval_assignment_expr(expr ast.AssignmentExpr, env *environment) RuntimeVal {
 	switch a := expr.Assigne.(type) {
+	case ast.SymbolExpr, ast.MemberAccessExpr, ast.ArrayAccessExpr, ast.SliceExpr:
+		// compound assignments read the target, apply their operator and
+		// store the result back, evaluating the target only once
+		ref := eval_reference(a, env)
+		if operator, ok := expr.Operator.CompoundOperator(); ok {
+			return ref.store(eval_binary_values(ref.load(), eval_expr(expr.Value, env), operator))
+		}
+		return ref.store(eval_expr(expr.Value, env))
+	case ast.TupleExpr:
+		if expr.Operator.Kind != lexer.ASSIGNMENT {
+			panic(fmt.Sprintf("Cannot use %s when destructuring a tuple", expr.Operator.Value))
+		}
+
+		val := eval_expr(expr.Value, env)
+		tuple, ok := val.(Tuple)
+		if !ok || len(tuple.Elements) != len(a.Elements) {
+			panic(fmt.Sprintf("Cannot destructure %s into %d targets", val.Inspect(), len(a.Elements)))
+		}
+
+		for i, target := range a.Elements {
+			assign_value(target, tuple.Elements[i], env)
+		}
+		return val
+	default:
+		panic("")
+	}
+}
+
+// assign_value stores an already evaluated value into an assignable target
+func assign_value(target ast.Expr, value RuntimeVal, env *environment) RuntimeVal {
+	return eval_reference(target, env).store(value)
+}
+
+// reference is an assignable target whose container, index and bounds are
+// evaluated, so that it can be read and written without evaluating them
+// again.
+type reference struct {
+	load  func() RuntimeVal
+	store func(value RuntimeVal) RuntimeVal
+}
+
+func eval_reference(target ast.Expr, env *environment) reference {
+	switch a := target.(type) {
 	case ast.SymbolExpr:
-		var val RuntimeVal
-		if expr.Operator.Kind == lexer.PLUS_EQUALS {
-			val = eval_binary_expr(ast.BinaryExpr{Left: expr.Assigne, Right: expr.Value, Operator: lexer.NewToken(lexer.PLUS, "+")}, env)
-		} else if expr.Operator.Kind == lexer.MINUS_EQUALS {
-			val = eval_binary_expr(ast.BinaryExpr{Left: expr.Assigne, Right: expr.Value, Operator: lexer.NewToken(lexer.PLUS, "-")}, env)
-		} else {
-			val = eval_expr(expr.Value, env)
+		return reference{
+			load: func() RuntimeVal { return eval_expr(a, env) },
+			store: func(value RuntimeVal) RuntimeVal {
+				if a.Value == "_" {
+					return value
+				}
+				return env.assignVar(a.Value, value)
+			},
 		}
-		return env.assignVar(a.Value, val)
 	case ast.MemberAccessExpr:
-		val := eval_expr(expr.Value, env)
-		return env.assignStruct(getBaseVariableName(a), a.Member, val)
+		// the member is written through its container, which shares its
+		// properties with the variable it was reached from
+		container := eval_expr(a.Struct, env)
+		return reference{
+			load: func() RuntimeVal { return memberValue(container, a, env) },
+			store: func(value RuntimeVal) RuntimeVal {
+				structVal, ok := container.(Struct)
+				if !ok {
+					panic(fmt.Sprintf("Cannot assign to member %s of %s", a.Member, container.Type()))
+				}
+				return env.assignMember(structVal, GetRootVariableName(a.Struct), a.Member, value)
+			},
+		}
 	case ast.ArrayAccessExpr:
-		array := eval_expr(a.Array, env)
+		container := eval_expr(a.Array, env)
 		index := eval_expr(a.Index, env)
-		value := eval_expr(expr.Value, env)
+		return reference{
+			load: func() RuntimeVal { return indexValue(container, index) },
+			store: func(value RuntimeVal) RuntimeVal {
+				checkMutable(container, GetRootVariableName(a.Array), env)
 
-		switch arr := array.(type) {
-		case Array:
-			if int(index.(Number).Value) >= len(arr.Elements) || int(index.(Number).Value) < 0 {
-				panic(fmt.Sprintf("Index out of range: %d", int(index.(Number).Value)))
-			}
-			arr.Elements[int(index.(Number).Value)] = value
-			return env.assignVar(a.Array.(ast.SymbolExpr).Value, arr)
-		default:
-			panic("")
+				switch arr := container.(type) {
+				case *Map:
+					arr.Set(index, value)
+					return value
+				case Array:
+					position, ok := index.(Number)
+					if !ok {
+						panic("Array index must be a number")
+					}
+					if !checkType(value.Type(), arr.ElementType) {
+						panic(fmt.Sprintf("Cannot assign %s to an element of %s", value.Type(), arr.Type()))
+					}
+					// the elements are shared with the array the index was
+					// taken from, so there is nothing to write back
+					arr.Elements[resolveIndex(int(position.Value), len(arr.Elements), "array")] = value
+					return value
+				default:
+					panic(fmt.Sprintf("Cannot assign to an element of %s", container.Type()))
+				}
+			},
+		}
+	case ast.SliceExpr:
+		// assigning a slice may change the length of the array, the new
+		// array is stored back into whatever the array was taken from
+		outer := eval_reference(a.Array, env)
+		sliced := outer.load()
+		bounds := eval_slice_bounds(a, env)
+		return reference{
+			load: func() RuntimeVal { return sliceValue(sliced, bounds) },
+			store: func(value RuntimeVal) RuntimeVal {
+				outer.store(assign_slice(sliced, bounds, GetRootVariableName(a.Array), value, env))
+				return value
+			},
 		}
 	default:
-		panic("")
+		panic(fmt.Sprintf("Cannot assign to %T", target))
+	}
+}
+
+// assign_slice returns the array with the elements selected by a slice of
+// it replaced. Without a step the replacement may have any length, with one
+// it must have as many elements as the slice selects. binding names the
+// variable the array was reached from.
+func assign_slice(sliced RuntimeVal, bounds sliceBounds, binding string, value RuntimeVal, env *environment) RuntimeVal {
+	arr, ok := sliced.(Array)
+	if !ok {
+		panic(fmt.Sprintf("Cannot assign to a slice of %s", sliced.Type()))
+	}
+	checkMutable(arr, binding, env)
+
+	replacement, ok := value.(Array)
+	if !ok {
+		panic(fmt.Sprintf("Cannot assign %s to a slice of %s", value.Type(), arr.Type()))
+	}
+	for _, element := range replacement.Elements {
+		if !checkType(element.Type(), arr.ElementType) {
+			panic(fmt.Sprintf("Cannot assign %s to an element of %s", element.Type(), arr.Type()))
+		}
+	}
+
+	start, end, step := bounds.resolve(len(arr.Elements))
+	elements := make([]RuntimeVal, 0, len(arr.Elements)+len(replacement.Elements))
+
+	if step == 1 {
+		end = max(start, end)
+		elements = append(elements, arr.Elements[:start]...)
+		elements = append(elements, replacement.Elements...)
+		elements = append(elements, arr.Elements[end:]...)
+	} else {
+		indices := sliceIndices(start, end, step)
+		if len(indices) != len(replacement.Elements) {
+			panic(fmt.Sprintf("Cannot assign %d elements to a slice of %d elements with step %d", len(replacement.Elements), len(indices), step))
+		}
+		elements = append(elements, arr.Elements...)
+		for i, index := range indices {
+			elements[index] = replacement.Elements[i]
+		}
+	}
+
+	return Array{Elements: elements, ElementType: arr.ElementType}
+}
+
+func eval_match_expr(m ast.MatchExpr, env *environment) RuntimeVal {
+	subject := eval_expr(m.Subject, env)
+
+	if enumVal, ok := subject.(Enum); ok {
+		checkExhaustive(enumVal.Def, m.Arms)
 	}
+
+	for _, arm := range m.Arms {
+		armEnv := &environment{Variables: make(map[string]Variable), Parent: env}
+
+		if !matchPattern(arm.Pattern, subject, armEnv, false) {
+			continue
+		}
+
+		if arm.Guard != nil && !truthify(eval_expr(arm.Guard, armEnv)) {
+			continue
+		}
+
+		return Evaluate(arm.Body, armEnv)
+	}
+
+	panic(fmt.Sprintf("Non-exhaustive match: no arm matches %s", subject.Inspect()))
 }
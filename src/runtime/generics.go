@@ -0,0 +1,107 @@
+package runtime
+
+func isTypeParam(t *TypeSpec, typeParams []string) bool {
+	if t.Kind != NamedKind || len(t.Args) > 0 {
+		return false
+	}
+
+	for _, param := range typeParams {
+		if t.Name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// unify checks actual against declared, binding the type parameters that
+// appear in declared on first use and checking them afterwards.
+func unify(declared *TypeSpec, actual *TypeSpec, typeParams []string, bindings map[string]*TypeSpec) bool {
+	if declared.Kind == NullableKind {
+		if actual.isNamed(NullType.Name) {
+			return true
+		}
+		if actual.Kind == NullableKind {
+			actual = actual.Elem
+		}
+		return unify(declared.Elem, actual, typeParams, bindings)
+	}
+
+	if isTypeParam(declared, typeParams) {
+		if actual.isNamed(NullType.Name) {
+			return true
+		}
+
+		bound, exists := bindings[declared.Name]
+		if !exists {
+			bindings[declared.Name] = actual
+			return true
+		}
+		return isAssignable(actual, bound)
+	}
+
+	if declared.Kind == FnKind && actual.Kind == FnKind {
+		if len(actual.Args) != len(declared.Args) {
+			return false
+		}
+		// unannotated parameters and results of the passed function accept
+		// whatever the declared signature asks for
+		for i := range declared.Args {
+			if !actual.Args[i].isNamed(AnyType.Name) && !unify(declared.Args[i], actual.Args[i], typeParams, bindings) {
+				return false
+			}
+		}
+		return actual.Elem.isNamed(AnyType.Name) || unify(declared.Elem, actual.Elem, typeParams, bindings)
+	}
+
+	if declared.Kind == NamedKind && actual.Kind == NamedKind && len(declared.Args) > 0 &&
+		declared.Name == actual.Name && len(declared.Args) == len(actual.Args) {
+		for i := range declared.Args {
+			if !actual.Args[i].isNamed(AnyType.Name) && !unify(declared.Args[i], actual.Args[i], typeParams, bindings) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return isAssignable(actual, substitute(declared, bindings))
+}
+
+// substitute replaces bound type parameters inside t.
+func substitute(t *TypeSpec, bindings map[string]*TypeSpec) *TypeSpec {
+	if t.Kind == NamedKind && len(t.Args) == 0 {
+		if bound, exists := bindings[t.Name]; exists {
+			spec := *bound
+			spec.Variadic = t.Variadic
+			return &spec
+		}
+		return t
+	}
+
+	result := *t
+	if t.Elem != nil {
+		result.Elem = substitute(t.Elem, bindings)
+	}
+	if t.Args != nil {
+		result.Args = make([]*TypeSpec, len(t.Args))
+		for i, arg := range t.Args {
+			result.Args[i] = substitute(arg, bindings)
+		}
+	}
+
+	// ?T bound to any or to an already nullable type needs no extra ?
+	if result.Kind == NullableKind && (result.Elem.Kind == NullableKind || result.Elem.isNamed(AnyType.Name)) {
+		return result.Elem
+	}
+	return &result
+}
+
+// bindTypeParams completes bindings so that every type parameter is bound,
+// falling back to any for parameters no value constrained.
+func bindTypeParams(typeParams []string, bindings map[string]*TypeSpec) map[string]*TypeSpec {
+	for _, param := range typeParams {
+		if _, exists := bindings[param]; !exists {
+			bindings[param] = AnyType
+		}
+	}
+	return bindings
+}
@@ -3,29 +3,113 @@ package runtime
 import (
 	"fmt"
 	"shiplang/src/ast"
+	"sort"
+	"strings"
 )
 
-func checkType(valType ValueType, expectedType ValueType) bool {
-	if expectedType == AnyType || valType == NullType {
-		return true
+// strictNulls is the null safety mode of the code currently running. It is
+// switched whenever evaluation enters code from another file.
+var strictNulls = false
+
+// currentModule is the scope of the file whose function is running, nil
+// while the top level of the main file runs.
+var currentModule *environment
+
+func runningModule(env *environment) *environment {
+	if currentModule != nil {
+		return currentModule
+	}
+	return env.module()
+}
+
+// checkVisible rejects access to a private property from outside the file
+// declaring its struct.
+func checkVisible(def StructDef, member string, env *environment) {
+	if isVisible(def, member, env) {
+		return
 	}
+	panic(fmt.Sprintf("Property %s of struct %s is private to its module", member, def.Name))
+}
 
-	return valType == expectedType
+// isVisible is checkVisible without the panic, used to skip private
+// properties when iterating over a struct.
+func isVisible(def StructDef, member string, env *environment) bool {
+	return def.Public[member] || def.Env == nil || def.Env.module() == runningModule(env)
 }
 
-func extractValueType(t ast.Type) ValueType {
+// checkType reports whether a value of type valType can be used where
+// expectedType is expected, see isAssignable for the rules.
+func checkType(valType *TypeSpec, expectedType *TypeSpec) bool {
+	return isAssignable(valType, expectedType)
+}
+
+func extractValueType(t ast.Type) *TypeSpec {
 
 	switch expType := t.(type) {
 	case ast.SymbolType:
-		return ValueType(expType.Name)
+		return namedType(expType.Name)
+	case ast.GenericType:
+		return namedType(expType.Name, extractValueTypes(expType.TypeArgs)...)
 	case ast.ArrayType:
 
-		return ValueType(fmt.Sprintf("array<%s>", extractValueType(expType.Underlying)))
+		return namedType(ArrayType.Name, extractValueType(expType.Underlying))
+	case ast.MapType:
+		return namedType(MapType.Name, extractValueType(expType.Key), extractValueType(expType.Value))
+	case ast.SetType:
+		return namedType(SetType.Name, extractValueType(expType.Underlying))
+	case ast.NullableType:
+		return nullableType(extractValueType(expType.Underlying))
+	case ast.FnType:
+		ret := AnyType
+		if expType.Return != nil {
+			ret = extractValueType(expType.Return)
+		}
+		return fnType(extractValueTypes(expType.Params), ret)
+	case ast.UnionType:
+		return unionType(extractValueTypes(expType.Members)...)
+	case ast.TupleType:
+		return namedType(TupleType.Name, extractValueTypes(expType.Elements)...)
 	default:
 		panic("Unsupported type for variable declaration")
 	}
 }
 
+func extractValueTypes(types []ast.Type) []*TypeSpec {
+	specs := make([]*TypeSpec, len(types))
+	for i, t := range types {
+		specs[i] = extractValueType(t)
+	}
+	return specs
+}
+
+func extractParameters(params []ast.Parameter) []Parameter {
+	result := make([]Parameter, len(params))
+
+	for i, param := range params {
+		result[i] = Parameter{Name: param.Name, Default: param.Default, Variadic: param.IsVariadic}
+
+		if param.IsVariadic {
+			result[i].Type = extractValueType(param.Type.(ast.ArrayType).Underlying)
+		} else {
+			result[i].Type = extractValueType(param.Type)
+		}
+	}
+
+	return result
+}
+
+// IsStrictDirective reports whether stmt is the "use strict"; directive that
+// opts a file into null safety.
+func IsStrictDirective(stmt ast.Stmt) bool {
+	exprStmt, ok := stmt.(ast.ExpressionStmt)
+	if !ok {
+		return false
+	}
+
+	str, ok := exprStmt.Expression.(ast.StringExpr)
+	return ok && str.Value == "use strict"
+}
+
 func truthify(val RuntimeVal) bool {
 	switch v := val.(type) {
 	case Number:
@@ -50,11 +134,142 @@ func equals(lhs RuntimeVal, rhs RuntimeVal) bool {
 	case String:
 		rhs, ok := rhs.(String)
 		return ok && lhs.Value == rhs.Value
+	case Null:
+		_, ok := rhs.(Null)
+		return ok
+	case Tuple:
+		rhs, ok := rhs.(Tuple)
+		if !ok || len(lhs.Elements) != len(rhs.Elements) {
+			return false
+		}
+		for i := range lhs.Elements {
+			if !equals(lhs.Elements[i], rhs.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		rhs, ok := rhs.(Array)
+		if !ok || len(lhs.Elements) != len(rhs.Elements) {
+			return false
+		}
+		for i := range lhs.Elements {
+			if !equals(lhs.Elements[i], rhs.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case Struct:
+		rhs, ok := rhs.(Struct)
+		if !ok || lhs.Name != rhs.Name || len(lhs.Properties) != len(rhs.Properties) {
+			return false
+		}
+		for name, value := range lhs.Properties {
+			other, exists := rhs.Properties[name]
+			if !exists || !equals(value, other) {
+				return false
+			}
+		}
+		return true
+	case Distinct:
+		rhs, ok := rhs.(Distinct)
+		return ok && lhs.TypeName == rhs.TypeName && equals(lhs.Value, rhs.Value)
+	case Enum:
+		rhs, ok := rhs.(Enum)
+		if !ok || lhs.Def.Name != rhs.Def.Name || lhs.Variant != rhs.Variant {
+			return false
+		}
+		for i := range lhs.Values {
+			if !equals(lhs.Values[i], rhs.Values[i]) {
+				return false
+			}
+		}
+		return true
 	default:
 		return false
 	}
 }
 
+// hashKey turns a map key or set element into a string that is equal for
+// structurally equal values. kind says what key is used as, for errors.
+// Arrays, maps and sets can change after being hashed, so they cannot be
+// keys and neither can structs, tuples and enums holding one.
+func hashKey(key RuntimeVal, kind string) string {
+	hash, unhashable := hashValue(key)
+	if unhashable == nil {
+		return hash
+	}
+
+	switch key.(type) {
+	case Struct, Tuple, Enum, Distinct:
+		panic(fmt.Sprintf("Cannot use %s as %s, it contains a value of type %s", key.Type(), kind, unhashable.Type()))
+	}
+	panic(fmt.Sprintf("Cannot use %s as %s", key.Type(), kind))
+}
+
+// hashValue hashes key for hashKey, or returns the value inside it that
+// cannot be hashed.
+func hashValue(key RuntimeVal) (string, RuntimeVal) {
+	switch k := key.(type) {
+	case Number:
+		return fmt.Sprintf("n:%g", k.Value), nil
+	case String:
+		return fmt.Sprintf("s:%q", k.Value), nil
+	case Bool:
+		return fmt.Sprintf("b:%t", k.Value), nil
+	case Null:
+		return "null", nil
+	case Struct:
+		names := make([]string, 0, len(k.Properties))
+		for name := range k.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fields := make([]string, len(names))
+		for i, name := range names {
+			hash, unhashable := hashValue(k.Properties[name])
+			if unhashable != nil {
+				return "", unhashable
+			}
+			fields[i] = fmt.Sprintf("%s=%s", name, hash)
+		}
+		return fmt.Sprintf("%s{%s}", k.Name, strings.Join(fields, ",")), nil
+	case Tuple:
+		elements, unhashable := hashValues(k.Elements)
+		if unhashable != nil {
+			return "", unhashable
+		}
+		return fmt.Sprintf("(%s)", elements), nil
+	case Enum:
+		values, unhashable := hashValues(k.Values)
+		if unhashable != nil {
+			return "", unhashable
+		}
+		return fmt.Sprintf("%s.%s(%s)", k.Def.Name, k.Variant, values), nil
+	case Distinct:
+		hash, unhashable := hashValue(k.Value)
+		if unhashable != nil {
+			return "", unhashable
+		}
+		return fmt.Sprintf("%s(%s)", k.TypeName, hash), nil
+	default:
+		return "", key
+	}
+}
+
+func hashValues(values []RuntimeVal) (string, RuntimeVal) {
+	hashes := make([]string, len(values))
+	for i, value := range values {
+		hash, unhashable := hashValue(value)
+		if unhashable != nil {
+			return "", unhashable
+		}
+		hashes[i] = hash
+	}
+	return strings.Join(hashes, ","), nil
+}
+
 func negate(r RuntimeVal) RuntimeVal {
 	switch r := r.(type) {
 	case Number:
@@ -66,19 +281,121 @@ func negate(r RuntimeVal) RuntimeVal {
 	}
 }
 
-func isPrimitive(v ValueType) bool {
-	return v == NullType || v == StringType || v == NumberType || v == BooleanType || (len(v) >= 5 && v[:5] == ArrayType)
+func isPrimitive(v *TypeSpec) bool {
+	if v.Kind != NamedKind {
+		return false
+	}
+
+	switch v.Name {
+	case NullType.Name, StringType.Name, NumberType.Name, BooleanType.Name, ArrayType.Name, MapType.Name, SetType.Name:
+		return true
+	}
+	return false
+}
+
+// freeze returns a deeply immutable copy of v. Values that cannot be
+// modified anyway are returned as they are.
+func freeze(v RuntimeVal) RuntimeVal {
+	switch v := v.(type) {
+	case Array:
+		if v.Frozen {
+			return v
+		}
+		elements := make([]RuntimeVal, len(v.Elements))
+		for i, element := range v.Elements {
+			elements[i] = freeze(element)
+		}
+		return Array{Elements: elements, ElementType: v.ElementType, Frozen: true}
+	case Struct:
+		if v.Frozen {
+			return v
+		}
+		properties := make(map[string]RuntimeVal, len(v.Properties))
+		for name, prop := range v.Properties {
+			properties[name] = freeze(prop)
+		}
+		return Struct{Name: v.Name, TypeArgs: v.TypeArgs, Properties: properties, Order: v.Order, Def: v.Def, Frozen: true}
+	case *Map:
+		if v.Frozen {
+			return v
+		}
+		m := NewMap(v.KeyType, v.ValueType)
+		for _, entry := range v.OrderedEntries() {
+			m.Set(entry.Key, freeze(entry.Value))
+		}
+		m.Frozen = true
+		return m
+	case *Set:
+		if v.Frozen {
+			return v
+		}
+		s := NewSet(v.ElementType)
+		for _, element := range v.OrderedElements() {
+			s.Add(element)
+		}
+		s.Frozen = true
+		return s
+	case Tuple:
+		elements := make([]RuntimeVal, len(v.Elements))
+		for i, element := range v.Elements {
+			elements[i] = freeze(element)
+		}
+		return Tuple{Elements: elements}
+	case Enum:
+		values := make([]RuntimeVal, len(v.Values))
+		for i, value := range v.Values {
+			values[i] = freeze(value)
+		}
+		return Enum{Def: v.Def, Variant: v.Variant, Values: values}
+	case Distinct:
+		return Distinct{TypeName: v.TypeName, Value: freeze(v.Value)}
+	}
+	return v
 }
 
-func getBaseVariableName(expr ast.MemberAccessExpr) string {
-	if symExpr, ok := expr.Struct.(ast.SymbolExpr); ok {
-		return symExpr.Value
-	} else if memberExpr, ok := expr.Struct.(ast.MemberAccessExpr); ok {
+func isFrozen(v RuntimeVal) bool {
+	switch v := v.(type) {
+	case Array:
+		return v.Frozen
+	case Struct:
+		return v.Frozen
+	case *Map:
+		return v.Frozen
+	case *Set:
+		return v.Frozen
+	}
+	return false
+}
+
+// checkMutable rejects changes to a frozen value, naming the binding the
+// change went through.
+func checkMutable(v RuntimeVal, binding string, env *environment) {
+	if !isFrozen(v) {
+		return
+	}
+	if binding == "" {
+		panic("Cannot modify a frozen value")
+	}
+	if env.lookupVar(binding).Constant {
+		panic(fmt.Sprintf("Cannot modify constant %s", binding))
+	}
+	panic(fmt.Sprintf("Cannot modify frozen value %s", binding))
+}
 
-		return getBaseVariableName(memberExpr)
-	} else {
-		return ""
+// GetRootVariableName finds the variable an access chain such as
+// a.b[0].c starts from.
+func GetRootVariableName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case ast.SymbolExpr:
+		return e.Value
+	case ast.MemberAccessExpr:
+		return GetRootVariableName(e.Struct)
+	case ast.ArrayAccessExpr:
+		return GetRootVariableName(e.Array)
+	case ast.SliceExpr:
+		return GetRootVariableName(e.Array)
 	}
+	return ""
 }
 
 func declareNativeValues(env environment) {
@@ -92,5 +409,6 @@ func declareNativeFunctions(env environment) {
 	env.declareNativeFn("time", timeFN)
 	env.declareNativeFn("date", dateFN)
 	env.declareNativeFn("range", rangeFN)
+	env.declareNativeFn("freeze", freezeFN)
 
 }
@@ -5,6 +5,15 @@ import (
 	"shiplang/src/ast"
 )
 
+var strictByDefault = false
+
+// SetStrict enables null safety for every file, as if each one started
+// with the "use strict"; directive.
+func SetStrict(enabled bool) {
+	strictByDefault = enabled
+	strictNulls = enabled
+}
+
 func Evaluate(node ast.Stmt, env *environment) RuntimeVal {
 	switch n := node.(type) {
 	case ast.ExpressionStmt:
@@ -17,6 +26,10 @@ func Evaluate(node ast.Stmt, env *environment) RuntimeVal {
 		return eval_struct_decl_stmt(n, env)
 	case ast.FnDeclStmt:
 		return eval_fn_decl_stmt(n, env)
+	case ast.EnumDeclStmt:
+		return eval_enum_decl_stmt(n, env)
+	case ast.TypeDeclStmt:
+		return eval_type_decl_stmt(n, env)
 	case ast.ImplStmt:
 		return eval_struct_impl_stmt(n, env)
 	case ast.BreakStmt:
@@ -25,8 +38,14 @@ func Evaluate(node ast.Stmt, env *environment) RuntimeVal {
 		return eval_return_stmt(n, env)
 	case ast.IfStmt:
 		return eval_if_stmt(n, env)
+	case ast.SwitchStmt:
+		return eval_switch_stmt(n, env)
 	case ast.WhileStmt:
 		return eval_while_stmt(n, env)
+	case ast.DoWhileStmt:
+		return eval_do_while_stmt(n, env)
+	case ast.LoopStmt:
+		return eval_loop_stmt(n, env)
 	case ast.ForStmt:
 		return eval_for_stmt(n, env)
 	case ast.ForeachStmt:
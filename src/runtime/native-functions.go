@@ -31,6 +31,13 @@ func inputFN(args []RuntimeVal) RuntimeVal {
 	return MKSTR(input)
 }
 
+func freezeFN(args []RuntimeVal) RuntimeVal {
+	if len(args) != 1 {
+		panic("freeze expects exactly 1 argument")
+	}
+	return freeze(args[0])
+}
+
 func showFN(args []RuntimeVal) RuntimeVal {
 	for i, arg := range args {
 		if i > 0 {
@@ -52,13 +59,31 @@ func showFN(args []RuntimeVal) RuntimeVal {
 				fmt.Print(element.Inspect())
 			}
 			fmt.Print("]")
+		case *Map:
+			fmt.Print("{")
+			for j, entry := range val.OrderedEntries() {
+				if j > 0 {
+					fmt.Print(", ")
+				}
+				fmt.Printf("%s: %s", entry.Key.Inspect(), entry.Value.Inspect())
+			}
+			fmt.Print("}")
+		case *Set:
+			fmt.Print("{")
+			for j, element := range val.OrderedElements() {
+				if j > 0 {
+					fmt.Print(", ")
+				}
+				fmt.Print(element.Inspect())
+			}
+			fmt.Print("}")
 		case Struct:
 			fmt.Println("{ ")
-			for propName, propVal := range val.Properties {
+			for _, propName := range val.Order {
 
 				fmt.Printf("  %s: ", propName)
 				var mslice []RuntimeVal
-				mslice = append(mslice, propVal)
+				mslice = append(mslice, val.Properties[propName])
 
 				showFN(mslice)
 
@@ -91,7 +116,7 @@ func rangeFN(args []RuntimeVal) RuntimeVal {
 	}
 
 	// Ensure the first argument is a number
-	if args[0].Type() != NumberType {
+	if !args[0].Type().Equal(NumberType) {
 		panic("range function expects a number as the first argument")
 	}
 
@@ -100,7 +125,7 @@ func rangeFN(args []RuntimeVal) RuntimeVal {
 
 	// If two arguments are provided, ensure the second argument is also a number
 	if len(args) == 2 {
-		if args[1].Type() != NumberType {
+		if !args[1].Type().Equal(NumberType) {
 			panic("range function expects a number as the second argument")
 		}
 		end = int(args[1].(Number).Value)
@@ -116,5 +141,5 @@ func rangeFN(args []RuntimeVal) RuntimeVal {
 		result[i] = MKNUM(float64(start + i))
 	}
 
-	return Array{Elements: result}
+	return Array{Elements: result, ElementType: NumberType}
 }
@@ -0,0 +1,150 @@
+package runtime
+
+import (
+	"fmt"
+	"shiplang/src/lexer"
+	"shiplang/src/parser"
+	"strings"
+	"testing"
+)
+
+// operands holds a left and a right sample of every kind of value the
+// binary operators are tested against.
+var operands = []struct {
+	name        string
+	left, right RuntimeVal
+}{
+	{"number", MKNUM(7), MKNUM(2)},
+	{"string", MKSTR("b"), MKSTR("a")},
+	{"boolean", MKBOOL(true), MKBOOL(false)},
+	{"null", MKNULL(), MKNULL()},
+	{"array", Array{Elements: []RuntimeVal{MKNUM(1)}, ElementType: NumberType}, Array{Elements: []RuntimeVal{MKNUM(2)}, ElementType: NumberType}},
+	{"struct", Struct{Name: "Point", Properties: map[string]RuntimeVal{"x": MKNUM(1)}, Order: []string{"x"}}, Struct{Name: "Point", Properties: map[string]RuntimeVal{"x": MKNUM(2)}, Order: []string{"x"}}},
+}
+
+var operators = []string{"+", "-", "*", "/", "%", "**", "div", "&", "|", "^", "<<", ">>", "<", "<=", ">", ">=", "==", "!=", "&&", "||"}
+
+// results lists the operand types each operator accepts, by operator, left
+// and right type, with the result for the samples in operands. Every other
+// pair is an error, except for the operators accepting any values.
+var results = map[string]string{
+	"+ number number":   "9",
+	"- number number":   "5",
+	"* number number":   "14",
+	"/ number number":   "3.5",
+	"% number number":   "1",
+	"** number number":  "49",
+	"div number number": "3",
+	"& number number":   "2",
+	"| number number":   "7",
+	"^ number number":   "5",
+	"<< number number":  "28",
+	">> number number":  "1",
+	"< number number":   "false",
+	"<= number number":  "false",
+	"> number number":   "true",
+	">= number number":  "true",
+	"+ string string":   "ba",
+	"< string string":   "false",
+	"<= string string":  "false",
+	"> string string":   "true",
+	">= string string":  "true",
+	"+ array array":     "[1, 2]",
+}
+
+// evalBinary evaluates l op r and returns the result, or the message of
+// the panic it raised.
+func evalBinary(op string, l RuntimeVal, r RuntimeVal) (result RuntimeVal, panicked string) {
+	defer func() {
+		if err := recover(); err != nil {
+			panicked = fmt.Sprint(err)
+		}
+	}()
+
+	env := NewEnv(nil)
+	env.declareVar("l", l, AnyType, false)
+	env.declareVar("r", r, AnyType, false)
+	return Evaluate(parser.Parse(lexer.Tokenize("l "+op+" r;")), env), ""
+}
+
+// inspect formats v as show prints it.
+func inspect(v RuntimeVal) string {
+	if arr, ok := v.(Array); ok {
+		elements := make([]string, len(arr.Elements))
+		for i, element := range arr.Elements {
+			elements[i] = inspect(element)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return v.Inspect()
+}
+
+func TestBinaryOperatorsOnEveryOperandPair(t *testing.T) {
+	for _, op := range operators {
+		for _, lhs := range operands {
+			for _, rhs := range operands {
+				name := fmt.Sprintf("%s %s %s", op, lhs.name, rhs.name)
+				t.Run(name, func(t *testing.T) {
+					result, panicked := evalBinary(op, lhs.left, rhs.right)
+
+					switch want, accepted := results[name]; {
+					case accepted:
+						if panicked != "" || inspect(result) != want {
+							t.Fatalf("got %v (panic %q), want %s", result, panicked, want)
+						}
+					case op == "==" || op == "!=" || op == "&&" || op == "||":
+						if _, isBool := result.(Bool); panicked != "" || !isBool {
+							t.Fatalf("got %v (panic %q), want a boolean", result, panicked)
+						}
+					default:
+						want := fmt.Sprintf("Operator %s cannot be applied to %s and %s", op, lhs.left.Type(), rhs.right.Type())
+						if panicked != want {
+							t.Fatalf("got %v (panic %q), want panic %q", result, panicked, want)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestBinaryOperatorValues(t *testing.T) {
+	tests := []struct {
+		op     string
+		l, r   RuntimeVal
+		want   string
+		panics string
+	}{
+		{op: "%", l: MKNUM(5), r: MKNUM(0.5), want: "0"},
+		{op: "%", l: MKNUM(7.5), r: MKNUM(2), want: "1.5"},
+		{op: "%", l: MKNUM(-7), r: MKNUM(2), want: "-1"},
+		{op: "%", l: MKNUM(7), r: MKNUM(-2), want: "1"},
+		{op: "%", l: MKNUM(1), r: MKNUM(0), panics: "Division by zero"},
+		{op: "/", l: MKNUM(1), r: MKNUM(0), panics: "Division by zero"},
+		{op: "div", l: MKNUM(1), r: MKNUM(0), panics: "Division by zero"},
+		{op: "div", l: MKNUM(-7), r: MKNUM(2), want: "-4"},
+		{op: "div", l: MKNUM(7.5), r: MKNUM(2), want: "3"},
+		{op: "**", l: MKNUM(9), r: MKNUM(0.5), want: "3"},
+		{op: "&", l: MKNUM(1.5), r: MKNUM(1), panics: "Operator & requires whole numbers, got 1.5"},
+		{op: "<<", l: MKNUM(1), r: MKNUM(-1), panics: "Shift count -1 cannot be negative"},
+		{op: ">>", l: MKNUM(-16), r: MKNUM(2), want: "-4"},
+		{op: "==", l: MKNULL(), r: MKNULL(), want: "true"},
+		{op: "==", l: MKNUM(1), r: MKSTR("1"), want: "false"},
+		{op: "!=", l: MKSTR("a"), r: MKSTR("a"), want: "false"},
+		{op: "&&", l: MKNUM(1), r: MKNULL(), want: "false"},
+		{op: "||", l: MKNULL(), r: MKSTR("a"), want: "true"},
+		{op: "+", l: Array{ElementType: NumberType}, r: Array{ElementType: StringType}, panics: "Operator + cannot be applied to array<number> and array<string>"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %s %s", inspect(test.l), test.op, inspect(test.r)), func(t *testing.T) {
+			result, panicked := evalBinary(test.op, test.l, test.r)
+			if panicked != test.panics {
+				t.Fatalf("got panic %q, want %q", panicked, test.panics)
+			}
+			if test.panics == "" && inspect(result) != test.want {
+				t.Fatalf("got %s, want %s", inspect(result), test.want)
+			}
+		})
+	}
+}
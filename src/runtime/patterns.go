@@ -0,0 +1,170 @@
+package runtime
+
+import (
+	"fmt"
+	"shiplang/src/ast"
+	"strings"
+)
+
+// matchPattern reports whether val matches pattern, declaring any bindings
+// the pattern introduces inside env. Bindings are declared constant when
+// the pattern comes from a const declaration.
+func matchPattern(pattern ast.Pattern, val RuntimeVal, env *environment, constant bool) bool {
+	switch p := pattern.(type) {
+	case ast.WildcardPattern:
+		return true
+	case ast.BindingPattern:
+		env.declareVar(p.Name, val, AnyType, constant)
+		return true
+	case ast.LiteralPattern:
+		return equals(eval_expr(p.Value, env), val)
+	case ast.VariantPattern:
+		enumDef := lookupEnumDef(p.EnumName, env)
+		variant, exists := enumDef.lookupVariant(p.Variant)
+
+		if !exists {
+			panic(fmt.Sprintf("Variant %s not found in enum %s", p.Variant, p.EnumName))
+		}
+
+		if len(p.Fields) != 0 && len(p.Fields) != len(variant.Fields) {
+			panic(fmt.Sprintf("Pattern %s.%s expects %d field(s), got %d", p.EnumName, p.Variant, len(variant.Fields), len(p.Fields)))
+		}
+
+		enumVal, ok := val.(Enum)
+		if !ok || enumVal.Def.Name != p.EnumName || enumVal.Variant != p.Variant {
+			return false
+		}
+
+		for i, field := range p.Fields {
+			if !matchPattern(field, enumVal.Values[i], env, constant) {
+				return false
+			}
+		}
+
+		return true
+	case ast.TuplePattern:
+		tuple, ok := val.(Tuple)
+		if !ok || len(tuple.Elements) != len(p.Elements) {
+			return false
+		}
+
+		for i, element := range p.Elements {
+			if !matchPattern(element, tuple.Elements[i], env, constant) {
+				return false
+			}
+		}
+
+		return true
+	case ast.ArrayPattern:
+		array, ok := val.(Array)
+		if !ok || len(array.Elements) < len(p.Elements) || (!p.HasRest && len(array.Elements) != len(p.Elements)) {
+			return false
+		}
+
+		for i, element := range p.Elements {
+			if !matchPattern(element, array.Elements[i], env, constant) {
+				return false
+			}
+		}
+
+		if p.HasRest {
+			rest := make([]RuntimeVal, len(array.Elements)-len(p.Elements))
+			copy(rest, array.Elements[len(p.Elements):])
+			env.declareVar(p.Rest, Array{Elements: rest, ElementType: array.ElementType}, AnyType, constant)
+		}
+
+		return true
+	case ast.StructPattern:
+		structVal, ok := val.(Struct)
+		if !ok || (p.StructName != "" && structVal.Name != p.StructName) {
+			return false
+		}
+
+		for _, field := range p.Fields {
+			propVal, exists := structVal.Properties[field.Name]
+			if !exists {
+				panic(fmt.Sprintf("Member %s not found in struct %s", field.Name, structVal.Name))
+			}
+			checkVisible(structVal.Def, field.Name, env)
+
+			if !matchPattern(field.Pattern, propVal, env, constant) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		panic(fmt.Sprintf("Unsupported pattern %T", p))
+	}
+}
+
+func lookupEnumDef(enumName string, env *environment) EnumDef {
+	enumDef, ok := env.lookupVar(enumName).Value.(EnumDef)
+	if !ok {
+		panic(fmt.Sprintf("%s is not an enum", enumName))
+	}
+	return enumDef
+}
+
+// IsIrrefutable reports whether pattern matches every value.
+func IsIrrefutable(pattern ast.Pattern) bool {
+	switch pattern.(type) {
+	case ast.WildcardPattern, ast.BindingPattern:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkExhaustive panics when the unguarded arms of a match over enumDef
+// leave at least one variant uncovered.
+func checkExhaustive(enumDef EnumDef, arms []ast.MatchArm) {
+	variants := make([]string, len(enumDef.Variants))
+	for i, variant := range enumDef.Variants {
+		variants[i] = variant.Name
+	}
+
+	if missing := MissingVariants(enumDef.Name, variants, arms); len(missing) > 0 {
+		panic(fmt.Sprintf("Non-exhaustive match on %s: missing %s", enumDef.Name, strings.Join(missing, ", ")))
+	}
+}
+
+// MissingVariants lists the variants of enumName that no unguarded arm
+// covers, none when an arm matches every value.
+func MissingVariants(enumName string, variants []string, arms []ast.MatchArm) []string {
+	covered := make(map[string]bool)
+
+	for _, arm := range arms {
+		if arm.Guard != nil {
+			continue
+		}
+
+		if IsIrrefutable(arm.Pattern) {
+			return nil
+		}
+
+		variantPattern, ok := arm.Pattern.(ast.VariantPattern)
+		if !ok || variantPattern.EnumName != enumName {
+			continue
+		}
+
+		allIrrefutable := true
+		for _, field := range variantPattern.Fields {
+			if !IsIrrefutable(field) {
+				allIrrefutable = false
+			}
+		}
+
+		if allIrrefutable {
+			covered[variantPattern.Variant] = true
+		}
+	}
+
+	var missing []string
+	for _, variant := range variants {
+		if !covered[variant] {
+			missing = append(missing, variant)
+		}
+	}
+	return missing
+}
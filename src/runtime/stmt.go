@@ -11,17 +11,27 @@ import (
 func eval_block_stmt(s ast.BlockStmt, env *environment) RuntimeVal {
 	last_evaluated := MKNULL()
 
+	if env.Parent == nil && len(s.Body) > 0 && IsStrictDirective(s.Body[0]) {
+		env.Strict = true
+		strictNulls = true
+	}
+
 	for _, s := range s.Body {
 		last_evaluated = Evaluate(s, env)
 
-		var leType ValueType = last_evaluated.Type()
+		var leType *TypeSpec = last_evaluated.Type()
 
-		if leType == BreakType {
+		if leType.Equal(BreakType) {
 			return Break{}
 		}
 
-		if leType == ReturnType {
-			return last_evaluated.(Return).Value
+		// returns propagate up to the enclosing function call, outside of a
+		// function they only end the current block
+		if leType.Equal(ReturnType) {
+			if !env.inFunction() {
+				return last_evaluated.(Return).Value
+			}
+			return last_evaluated
 		}
 
 	}
@@ -39,36 +49,196 @@ func eval_var_decl_stmt(decl ast.VarDeclStmt, env *environment) RuntimeVal {
 		val = eval_expr(decl.AssignedValue, env)
 	}
 
-	switch expTyp := decl.ExplicitType.(type) {
-	case ast.SymbolType:
-		env.declareVar(decl.VarName, val, ValueType(expTyp.Name), decl.IsConstant)
-	case ast.ArrayType:
-		env.declareVar(decl.VarName, val, extractValueType(expTyp), decl.IsConstant)
-	default:
-		env.declareVar(decl.VarName, val, AnyType, decl.IsConstant)
+	// constants are deeply immutable
+	if decl.IsConstant {
+		val = freeze(val)
+	}
+
+	var expectedType = AnyType
+	if decl.ExplicitType != nil {
+		expectedType = env.resolveType(decl.ExplicitType)
+	} else if decl.AssignedValue != nil {
+		expectedType = InferType(decl.AssignedValue, val.Type(), returnType(decl.AssignedValue, val, env))
+	}
+
+	if decl.Pattern != nil {
+		if !checkType(val.Type(), expectedType) {
+			panic(fmt.Sprintf("expected %s got %s", expectedType, val.Type()))
+		}
+
+		if !matchPattern(decl.Pattern, val, env, decl.IsConstant) {
+			panic(fmt.Sprintf("Cannot destructure value %s", val.Inspect()))
+		}
+
+		return val
+	}
+
+	env.declareVar(decl.VarName, val, expectedType, decl.IsConstant)
+	if decl.Exported {
+		env.export(decl.VarName)
 	}
 
 	return val
 }
 
+// InferType gives an un-annotated declaration the type of its initializer
+// when that type is evident from the code: literals, instantiations and
+// calls to functions declaring their return type. initType is the type of
+// the initializer and returnType the one declared by the function it calls,
+// nil when that is not known. Everything else, and `: any`, leaves the
+// variable free to hold any value.
+func InferType(init ast.Expr, initType *TypeSpec, returnType *TypeSpec) *TypeSpec {
+	switch e := init.(type) {
+	case ast.NumberExpr, ast.StringExpr, ast.TupleExpr, ast.ArrayInstantiationExpr,
+		ast.MapInstantiationExpr, ast.SetInstantiationExpr, ast.StructInstantiationExpr:
+		return initType
+	case ast.SymbolExpr:
+		if e.Value == "true" || e.Value == "false" {
+			return BooleanType
+		}
+	case ast.PrefixExpr:
+		if _, isNumber := e.RightExpr.(ast.NumberExpr); isNumber && e.Operator.Kind == lexer.DASH {
+			return NumberType
+		}
+	case ast.CallExpr:
+		if returnType != nil {
+			return returnType
+		}
+	}
+
+	return AnyType
+}
+
+// returnType is the return type declared by the function init calls, with
+// generic results only known through the value.
+func returnType(init ast.Expr, val RuntimeVal, env *environment) *TypeSpec {
+	call, ok := init.(ast.CallExpr)
+	if !ok {
+		return nil
+	}
+
+	fn, ok := calledFunction(call, env)
+	if !ok || fn.ReturnType == nil || fn.ReturnType.isNamed(AnyType.Name) {
+		return nil
+	}
+	if !substitute(fn.ReturnType, bindTypeParams(fn.TypeParams, make(map[string]*TypeSpec))).Equal(fn.ReturnType) {
+		return val.Type()
+	}
+	return fn.ReturnType
+}
+
+// calledFunction finds the function a call went to without evaluating any
+// part of it again, which limits method calls to those on variables.
+func calledFunction(call ast.CallExpr, env *environment) (Function, bool) {
+	if call.Callee != nil {
+		return Function{}, false
+	}
+
+	if call.Struct == nil {
+		fn := env.lookupCallable(call.FunctionName)
+		return fn, true
+	}
+
+	receiver, ok := call.Struct.(ast.SymbolExpr)
+	if !ok {
+		return Function{}, false
+	}
+
+	structVal, ok := env.lookupVar(receiver.Value).Value.(Struct)
+	if !ok {
+		return Function{}, false
+	}
+	fn, exists := structVal.Def.Methods[call.FunctionName]
+	return fn, exists
+}
+
 func eval_struct_decl_stmt(decl ast.StructDeclStmt, env *environment) RuntimeVal {
-	props := make(map[string]ValueType)
+	structDef := StructDef{
+		Name:       decl.StructName,
+		TypeParams: decl.TypeParams,
+		Properties: make(map[string]*TypeSpec),
+		Order:      decl.Order,
+		Defaults:   make(map[string]ast.Expr),
+		Required:   make(map[string]bool),
+		Readonly:   make(map[string]bool),
+		Embedded:   decl.Embedded,
+		Public:     make(map[string]bool),
+	}
+
+	for _, name := range decl.Order {
+		prop := decl.Properties[name]
+		structDef.Properties[name] = env.expandAliases(extractValueType(prop.Type), decl.TypeParams)
+		if prop.Default != nil {
+			structDef.Defaults[name] = prop.Default
+		}
+		if prop.Required {
+			structDef.Required[name] = true
+		}
+		if prop.Public {
+			structDef.Public[name] = true
+		}
+		if prop.Readonly {
+			structDef.Readonly[name] = true
+		}
+	}
+
+	// properties promoted from two embedded structs would be ambiguous,
+	// unless the struct declares its own property of that name
+	owners := make(map[string]string)
+	for _, embedded := range decl.Embedded {
+		embeddedDef, ok := env.lookupStruct(embedded).(StructDef)
+		if !ok {
+			panic(fmt.Sprintf("Cannot embed %s in struct %s, it is not a struct", embedded, decl.StructName))
+		}
+
+		for _, name := range embeddedDef.Order {
+			if _, shadowed := structDef.Properties[name]; shadowed {
+				continue
+			}
+			if owner, exists := owners[name]; exists {
+				panic(fmt.Sprintf("Struct %s embeds both %s and %s which define %s", decl.StructName, owner, embedded, name))
+			}
+			owners[name] = embedded
+		}
+	}
+
+	if decl.Exported {
+		env.export(decl.StructName)
+	}
+
+	return env.declareStruct(structDef)
+}
 
-	for name, prop := range decl.Properties {
-		switch p := prop.Type.(type) {
-		case ast.SymbolType:
-			props[name] = ValueType(p.Name)
-		case ast.ArrayType:
-			props[name] = ValueType(extractValueType(p))
+func eval_enum_decl_stmt(decl ast.EnumDeclStmt, env *environment) RuntimeVal {
+	variants := make([]EnumVariant, len(decl.Variants))
 
+	for i, variant := range decl.Variants {
+		for _, field := range variant.Fields {
+			if field.Default != nil || field.IsVariadic {
+				panic(fmt.Sprintf("Field %s of %s.%s cannot have a default value or be variadic", field.Name, decl.EnumName, variant.Name))
+			}
 		}
+		variants[i] = EnumVariant{Name: variant.Name, Fields: env.resolveParameters(extractParameters(variant.Fields), nil)}
 	}
 
-	return env.declareStruct(decl.StructName, props)
+	enumDef := EnumDef{Name: decl.EnumName, Variants: variants}
+	env.declareVar(decl.EnumName, enumDef, EnumType, true)
+	if decl.Exported {
+		env.export(decl.EnumName)
+	}
+
+	return enumDef
+}
+
+func eval_type_decl_stmt(decl ast.TypeDeclStmt, env *environment) RuntimeVal {
+	if decl.Exported {
+		env.export(decl.TypeName)
+	}
+	return env.declareType(decl.TypeName, extractValueType(decl.Underlying), decl.Distinct)
 }
 
 func eval_struct_impl_stmt(impl ast.ImplStmt, env *environment) RuntimeVal {
-	m := eval_impl_fn(impl.Method, env)
+	m := eval_impl_fn(impl.Method, env.lookupStruct(impl.Struct).(StructDef).TypeParams, env)
 	method, isFunc := m.(Function)
 
 	if isFunc {
@@ -77,57 +247,58 @@ func eval_struct_impl_stmt(impl ast.ImplStmt, env *environment) RuntimeVal {
 	panic("")
 }
 
-func eval_impl_fn(decl ast.FnDeclStmt, env *environment) RuntimeVal {
-	params := make([]Parameter, len(decl.Parameters))
-
-	for i, param := range decl.Parameters {
-		switch p := param.Type.(type) {
-		case ast.SymbolType:
-			params[i] = Parameter{Name: param.Name, Type: ValueType(p.Name)}
-		case ast.ArrayType:
-			params[i] = Parameter{Name: param.Name, Type: ValueType(extractValueType(p))}
-
-		}
-	}
+func eval_impl_fn(decl ast.FnDeclStmt, structTypeParams []string, env *environment) RuntimeVal {
+	// methods may refer to the type parameters of their struct
+	typeParams := append(append([]string{}, structTypeParams...), decl.TypeParams...)
+	params := env.resolveParameters(extractParameters(decl.Parameters), typeParams)
 
 	fn := Function{
 		Name:       decl.FnName,
+		TypeParams: decl.TypeParams,
 		Parameters: params,
+		ReturnType: AnyType,
 		Body:       decl.Body,
 		Env:        env,
 	}
 
+	if decl.ReturnType != nil {
+		fn.ReturnType = env.expandAliases(extractValueType(decl.ReturnType), typeParams)
+	}
+
 	return fn
 }
 
 func eval_fn_decl_stmt(decl ast.FnDeclStmt, env *environment) RuntimeVal {
-	params := make([]Parameter, len(decl.Parameters))
-
-	for i, param := range decl.Parameters {
-		switch p := param.Type.(type) {
-		case ast.SymbolType:
-			params[i] = Parameter{Name: param.Name, Type: ValueType(p.Name)}
-		case ast.ArrayType:
-			params[i] = Parameter{Name: param.Name, Type: ValueType(extractValueType(p))}
-
-		}
-	}
+	params := env.resolveParameters(extractParameters(decl.Parameters), decl.TypeParams)
 
 	fn := Function{
 		Name:       decl.FnName,
+		TypeParams: decl.TypeParams,
 		Parameters: params,
+		ReturnType: AnyType,
 		Body:       decl.Body,
 		Env:        env,
 	}
 
+	if decl.ReturnType != nil {
+		fn.ReturnType = env.expandAliases(extractValueType(decl.ReturnType), decl.TypeParams)
+	}
+
 	env.declareFn(fn)
+	if decl.Exported {
+		env.export(decl.FnName)
+	}
 
 	return fn
 }
 
 func eval_return_stmt(r ast.ReturnStmt, env *environment) RuntimeVal {
+	if r.Value == nil {
+		return Return{Value: MKNULL(), Line: r.Line}
+	}
+
 	value := eval_expr(r.Value, env)
-	return Return{Value: value}
+	return Return{Value: value, Line: r.Line}
 }
 
 func eval_if_stmt(i ast.IfStmt, env *environment) RuntimeVal {
@@ -137,10 +308,10 @@ func eval_if_stmt(i ast.IfStmt, env *environment) RuntimeVal {
 		return eval_block_stmt(i.IfBody, env)
 	}
 
-	for cond, body := range i.ElifBodies {
-		if truthify(eval_expr(cond, env)) {
+	for _, elif := range i.ElifBodies {
+		if truthify(eval_expr(elif.Condition, env)) {
 
-			return eval_block_stmt(body, env)
+			return eval_block_stmt(elif.Body, env)
 		}
 	}
 
@@ -148,13 +319,74 @@ func eval_if_stmt(i ast.IfStmt, env *environment) RuntimeVal {
 
 }
 
+// eval_switch_stmt evaluates the subject once and runs the first case with a
+// matching label. Values match by structural equality, ranges include both
+// bounds. break and return inside a case apply to the enclosing loop or
+// function.
+func eval_switch_stmt(sw ast.SwitchStmt, env *environment) RuntimeVal {
+	subject := eval_expr(sw.Subject, env)
+
+	for _, c := range sw.Cases {
+		for _, label := range c.Labels {
+			if caseMatches(subject, label, env) {
+				return eval_block_stmt(c.Body, env)
+			}
+		}
+	}
+
+	return eval_block_stmt(sw.Default, env)
+}
+
+func caseMatches(subject RuntimeVal, label ast.CaseLabel, env *environment) bool {
+	value := eval_expr(label.Value, env)
+	if label.Upper == nil {
+		return equals(subject, value)
+	}
+
+	upper := eval_expr(label.Upper, env)
+	switch lower := value.(type) {
+	case Number:
+		if upper, ok := upper.(Number); ok {
+			s, ok := subject.(Number)
+			return ok && lower.Value <= s.Value && s.Value <= upper.Value
+		}
+	case String:
+		if upper, ok := upper.(String); ok {
+			s, ok := subject.(String)
+			return ok && lower.Value <= s.Value && s.Value <= upper.Value
+		}
+	}
+
+	panic(fmt.Sprintf("Case range must be bounded by two numbers or two strings, got %s and %s", value.Type(), upper.Type()))
+}
+
 func eval_while_stmt(w ast.WhileStmt, env *environment) RuntimeVal {
 	for {
 		if !truthify(eval_expr(w.Condition, env)) {
 			break
 		}
 		value := eval_block_stmt(w.Body, env)
-		if value.Type() == BreakType {
+		if value.Type().Equal(BreakType) {
+			break
+		}
+		if value.Type().Equal(ReturnType) {
+			return value
+		}
+	}
+
+	return MKNULL()
+}
+
+func eval_do_while_stmt(d ast.DoWhileStmt, env *environment) RuntimeVal {
+	for {
+		value := eval_block_stmt(d.Body, env)
+		if value.Type().Equal(BreakType) {
+			break
+		}
+		if value.Type().Equal(ReturnType) {
+			return value
+		}
+		if !truthify(eval_expr(d.Condition, env)) {
 			break
 		}
 	}
@@ -162,63 +394,142 @@ func eval_while_stmt(w ast.WhileStmt, env *environment) RuntimeVal {
 	return MKNULL()
 }
 
+func eval_loop_stmt(l ast.LoopStmt, env *environment) RuntimeVal {
+	for {
+		value := eval_block_stmt(l.Body, env)
+		if value.Type().Equal(BreakType) {
+			break
+		}
+		if value.Type().Equal(ReturnType) {
+			return value
+		}
+	}
+
+	return MKNULL()
+}
+
 func eval_for_stmt(f ast.ForStmt, env *environment) RuntimeVal {
 	loopEnv := &environment{Variables: make(map[string]Variable), Parent: env}
 
-	if f.Init == nil || f.Cond == nil || f.Post == nil {
-		panic("")
+	if f.Init != nil {
+		Evaluate(f.Init, loopEnv)
 	}
-
-	Evaluate(f.Init, loopEnv)
 	for {
-		cond := eval_expr(f.Cond, loopEnv)
-		if !truthify(cond) {
+		if f.Cond != nil && !truthify(eval_expr(f.Cond, loopEnv)) {
 			break
 		}
 
 		val := eval_block_stmt(f.Body, loopEnv)
 
-		if val.Type() == BreakType {
+		if val.Type().Equal(BreakType) {
 			break
 		}
+		if val.Type().Equal(ReturnType) {
+			return val
+		}
 
-		Evaluate(f.Post, loopEnv)
+		if f.Post != nil {
+			Evaluate(f.Post, loopEnv)
+		}
 	}
 
 	return MKNULL()
 }
 
+// eval_foreach_stmt binds each element of the collection in turn. With a
+// key variable arrays, strings and sets also give the index (the byte offset
+// for strings, as for slicing), maps give key and value and structs give
+// the name and value of each property visible here. Typed loop variables
+// check every element.
 func eval_foreach_stmt(fe ast.ForeachStmt, env *environment) RuntimeVal {
 
 	collection := eval_expr(fe.Collection, env)
 	loopEnv := &environment{Variables: make(map[string]Variable), Parent: env}
 
-	loopEnv.declareVar(fe.Iterator, MKNULL(), AnyType, false)
+	keyType, iteratorType := AnyType, AnyType
+	if fe.KeyType != nil {
+		keyType = env.resolveType(fe.KeyType)
+	}
+	if fe.IteratorType != nil {
+		iteratorType = env.resolveType(fe.IteratorType)
+	}
+
+	// run executes the body for one element, done is set on break or return
+	run := func(key RuntimeVal, value RuntimeVal) (result RuntimeVal, done bool) {
+		if fe.KeyIterator != "" {
+			bindIterator(loopEnv, fe.KeyIterator, keyType, key)
+		}
+		bindIterator(loopEnv, fe.Iterator, iteratorType, value)
+
+		val := eval_block_stmt(fe.Body, loopEnv)
+		switch val.(type) {
+		case Break:
+			return MKNULL(), true
+		case Return:
+			return val, true
+		}
+		return nil, false
+	}
 
 	switch collection := collection.(type) {
 	case Array:
-		for _, item := range collection.Elements {
-			loopEnv.assignVar(fe.Iterator, item)
-			val := eval_block_stmt(fe.Body, loopEnv)
-			if val.Type() == BreakType {
-				break
+		for i, item := range collection.Elements {
+			if result, done := run(MKNUM(float64(i)), item); done {
+				return result
 			}
 		}
 	case String:
-		for _, char := range collection.Value {
-			loopEnv.assignVar(fe.Iterator, MKSTR(string(char)))
-			val := eval_block_stmt(fe.Body, loopEnv)
-			if val.Type() == BreakType {
-				break
+		// indices count characters rather than bytes
+		for i, char := range []rune(collection.Value) {
+			if result, done := run(MKNUM(float64(i)), MKSTR(string(char))); done {
+				return result
+			}
+		}
+	case *Set:
+		for i, element := range collection.OrderedElements() {
+			if result, done := run(MKNUM(float64(i)), element); done {
+				return result
+			}
+		}
+	case *Map:
+		for _, entry := range collection.OrderedEntries() {
+			value := entry.Value
+			if fe.KeyIterator == "" {
+				value = entry.Key
+			}
+			if result, done := run(entry.Key, value); done {
+				return result
+			}
+		}
+	case Struct:
+		for _, name := range collection.Order {
+			if !isVisible(collection.Def, name, env) {
+				continue
+			}
+
+			value := collection.Properties[name]
+			if fe.KeyIterator == "" {
+				value = MKSTR(name)
+			}
+			if result, done := run(MKSTR(name), value); done {
+				return result
 			}
 		}
 	default:
-		panic("")
+		panic(fmt.Sprintf("Cannot iterate over %s", collection.Type()))
 	}
 
 	return MKNULL()
 }
 
+// bindIterator sets a foreach variable to the next element
+func bindIterator(env *environment, name string, expected *TypeSpec, value RuntimeVal) {
+	if !checkType(value.Type(), expected) {
+		panic(fmt.Sprintf("Foreach variable %s expected %s got %s", name, expected, value.Type()))
+	}
+	env.Variables[name] = Variable{Value: value, ExpectedType: expected}
+}
+
 func eval_import_stmt(im ast.ImportStmt, env *environment) RuntimeVal {
 
 	bytes, err := os.ReadFile(im.FilePath)
@@ -232,8 +543,14 @@ func eval_import_stmt(im ast.ImportStmt, env *environment) RuntimeVal {
 
 	if len(im.Modules) > 0 {
 		moduleEnv := NewEnv(nil)
+
+		// the module runs in its own null safety mode
+		previous, previousModule := strictNulls, currentModule
+		strictNulls, currentModule = strictByDefault, moduleEnv
 		Evaluate(ast, moduleEnv)
-		env.addImport(moduleEnv, im.Modules)
+		strictNulls, currentModule = previous, previousModule
+
+		env.addImport(moduleEnv, im.Modules, im.FilePath)
 	} else {
 		Evaluate(ast, env)
 	}
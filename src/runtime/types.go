@@ -0,0 +1,391 @@
+package runtime
+
+import (
+	"fmt"
+	"shiplang/src/ast"
+	"strings"
+)
+
+type TypeKind int
+
+const (
+	NamedKind    TypeKind = iota // number, Pair<A, B>, array<string>
+	NullableKind                 // ?T
+	UnionKind                    // A | B
+	FnKind                       // fn(A, ...B): C
+)
+
+// TypeSpec is the type of a value or a declaration. Specs are compared
+// structurally and printed with String, which is what typeof and error
+// messages show.
+type TypeSpec struct {
+	Kind     TypeKind
+	Name     string      // named types only
+	Args     []*TypeSpec // type arguments, union members or fn parameters
+	Elem     *TypeSpec   // inner type of a nullable, result of a fn
+	Variadic bool        // fn parameter written ...T
+}
+
+func namedType(name string, args ...*TypeSpec) *TypeSpec {
+	return &TypeSpec{Kind: NamedKind, Name: name, Args: args}
+}
+
+func nullableType(elem *TypeSpec) *TypeSpec {
+	return &TypeSpec{Kind: NullableKind, Elem: elem}
+}
+
+// unionType joins members into a union, flattening the members that are
+// unions themselves.
+func unionType(members ...*TypeSpec) *TypeSpec {
+	if len(members) == 1 {
+		return members[0]
+	}
+
+	union := &TypeSpec{Kind: UnionKind}
+	for _, member := range members {
+		if member.Kind == UnionKind {
+			union.Args = append(union.Args, member.Args...)
+		} else {
+			union.Args = append(union.Args, member)
+		}
+	}
+	return union
+}
+
+func fnType(params []*TypeSpec, ret *TypeSpec) *TypeSpec {
+	return &TypeSpec{Kind: FnKind, Args: append([]*TypeSpec{}, params...), Elem: ret}
+}
+
+// parseType reads the textual form of a type, as printed by String.
+func parseType(t string) *TypeSpec {
+	p := &typeParser{src: t}
+	spec := p.parseUnion()
+	p.skipSpaces()
+	if p.pos != len(p.src) {
+		panic(fmt.Sprintf("Malformed type %s", t))
+	}
+	return spec
+}
+
+// Equal reports whether t and other are the same type.
+func (t *TypeSpec) Equal(other *TypeSpec) bool {
+	if t == other {
+		return true
+	}
+	if t == nil || other == nil || t.Kind != other.Kind || t.Name != other.Name ||
+		t.Variadic != other.Variadic || len(t.Args) != len(other.Args) || !t.Elem.Equal(other.Elem) {
+		return false
+	}
+	for i := range t.Args {
+		if !t.Args[i].Equal(other.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *TypeSpec) isNamed(name string) bool {
+	return t.Kind == NamedKind && t.Name == name && len(t.Args) == 0
+}
+
+func (t *TypeSpec) String() string {
+	switch t.Kind {
+	case NullableKind:
+		if t.Elem.Kind == UnionKind {
+			return fmt.Sprintf("?(%s)", t.Elem)
+		}
+		return "?" + t.Elem.String()
+	case UnionKind:
+		members := make([]string, len(t.Args))
+		for i, member := range t.Args {
+			members[i] = member.String()
+		}
+		return strings.Join(members, " | ")
+	case FnKind:
+		params := make([]string, len(t.Args))
+		for i, param := range t.Args {
+			params[i] = param.String()
+		}
+		result := t.Elem.String()
+		if t.Elem.Kind == UnionKind {
+			result = "(" + result + ")"
+		}
+		return fmt.Sprintf("fn(%s): %s", strings.Join(params, ", "), result)
+	}
+
+	name := t.Name
+	if t.Variadic {
+		name = "..." + name
+	}
+	if len(t.Args) == 0 {
+		return name
+	}
+
+	args := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s<%s>", name, strings.Join(args, ", "))
+}
+
+// isAssignable reports whether a value of type from can be stored where to
+// is expected. It is the single compatibility rule of the runtime:
+//   - any accepts everything, nested any (array<any>, fn(any)) is gradual
+//     and is compatible in both directions
+//   - null is accepted everywhere unless null safety is on, then only by
+//     nullable types
+//   - a union accepts each of its members, and is accepted where all of its
+//     members are
+//   - fn types need the same arity, parameters are compared contravariantly
+//     and results covariantly, the bare function type accepts them all
+//   - named types need the same name, type arguments are compared pairwise
+//     and a bare array, map or set accepts any element types
+func isAssignable(from *TypeSpec, to *TypeSpec) bool {
+	if to.isNamed(AnyType.Name) || from.Equal(to) {
+		return true
+	}
+
+	if from.Kind == UnionKind {
+		for _, member := range from.Args {
+			if !isAssignable(member, to) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if from.isNamed(NullType.Name) {
+		return !strictNulls || acceptsNull(to)
+	}
+
+	switch to.Kind {
+	case UnionKind:
+		for _, member := range to.Args {
+			if isAssignable(from, member) {
+				return true
+			}
+		}
+		return false
+	case NullableKind:
+		if from.Kind == NullableKind {
+			return isAssignable(from.Elem, to.Elem)
+		}
+		return isAssignable(from, to.Elem)
+	case FnKind:
+		if from.Kind != FnKind || len(from.Args) != len(to.Args) {
+			return false
+		}
+		for i := range to.Args {
+			if from.Args[i].Variadic != to.Args[i].Variadic || !isCompatible(to.Args[i], from.Args[i]) {
+				return false
+			}
+		}
+		return isCompatible(from.Elem, to.Elem)
+	}
+
+	if from.Kind == NullableKind {
+		return !strictNulls && isAssignable(from.Elem, to)
+	}
+
+	if to.isNamed(FunctionType.Name) {
+		return from.Kind == FnKind
+	}
+
+	if from.Kind != NamedKind || from.Name != to.Name {
+		return false
+	}
+
+	if len(to.Args) == 0 {
+		return len(from.Args) == 0 || to.Name == ArrayType.Name || to.Name == MapType.Name || to.Name == SetType.Name
+	}
+
+	if len(from.Args) != len(to.Args) {
+		return false
+	}
+	for i := range to.Args {
+		if !isCompatible(from.Args[i], to.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCompatible is used for nested types, where any on either side is
+// accepted.
+func isCompatible(from *TypeSpec, to *TypeSpec) bool {
+	return from.isNamed(AnyType.Name) || isAssignable(from, to)
+}
+
+func acceptsNull(t *TypeSpec) bool {
+	switch t.Kind {
+	case NullableKind:
+		return true
+	case UnionKind:
+		for _, member := range t.Args {
+			if acceptsNull(member) {
+				return true
+			}
+		}
+		return false
+	}
+	return t.isNamed(NullType.Name) || t.isNamed(AnyType.Name)
+}
+
+// typeParser reads the textual form of a type, which the checker meets in
+// typeof comparisons.
+type typeParser struct {
+	src string
+	pos int
+}
+
+func (p *typeParser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *typeParser) accept(s string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *typeParser) expect(s string) {
+	if !p.accept(s) {
+		panic(fmt.Sprintf("Malformed type %s, expected %s at offset %d", p.src, s, p.pos))
+	}
+}
+
+func (p *typeParser) parseUnion() *TypeSpec {
+	first := p.parsePrefix()
+	if !p.accept("|") {
+		return first
+	}
+
+	members := []*TypeSpec{first}
+	for {
+		members = append(members, p.parsePrefix())
+		if !p.accept("|") {
+			return unionType(members...)
+		}
+	}
+}
+
+func (p *typeParser) parsePrefix() *TypeSpec {
+	if p.accept("?") {
+		return &TypeSpec{Kind: NullableKind, Elem: p.parsePrefix()}
+	}
+
+	if p.accept("...") {
+		spec := *p.parsePrefix()
+		spec.Variadic = true
+		return &spec
+	}
+
+	if p.accept("(") {
+		inner := p.parseUnion()
+		p.expect(")")
+		return inner
+	}
+
+	name := p.parseName()
+	if name == "fn" && p.accept("(") {
+		fn := &TypeSpec{Kind: FnKind, Args: []*TypeSpec{}}
+		for !p.accept(")") {
+			fn.Args = append(fn.Args, p.parseUnion())
+			if !p.accept(",") {
+				p.expect(")")
+				break
+			}
+		}
+
+		fn.Elem = AnyType
+		if p.accept(":") {
+			fn.Elem = p.parsePrefix()
+		}
+		return fn
+	}
+
+	spec := &TypeSpec{Kind: NamedKind, Name: name}
+	if p.accept("<") && !p.accept(">") {
+		for {
+			spec.Args = append(spec.Args, p.parseUnion())
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.expect(">")
+	}
+	return spec
+}
+
+func (p *typeParser) parseName() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	if start == p.pos {
+		panic(fmt.Sprintf("Malformed type %s, expected a type name at offset %d", p.src, p.pos))
+	}
+	return p.src[start:p.pos]
+}
+
+// The functions below expose the type model to tools that work on the AST
+// without running it, such as the checker package.
+
+// ParseType reads a type written as typeof prints it.
+func ParseType(t string) *TypeSpec {
+	return parseType(t)
+}
+
+// TypeOf converts a type annotation into the TypeSpec the runtime uses for
+// it.
+func TypeOf(t ast.Type) *TypeSpec {
+	return extractValueType(t)
+}
+
+// IsAssignable reports whether a value of type from can be used where to is
+// expected, with null safety on or off.
+func IsAssignable(from *TypeSpec, to *TypeSpec, strict bool) bool {
+	previous := strictNulls
+	strictNulls = strict
+	defer func() { strictNulls = previous }()
+
+	return isAssignable(from, to)
+}
+
+// SubstituteType replaces the type names bound in bindings inside t.
+func SubstituteType(t *TypeSpec, bindings map[string]*TypeSpec) *TypeSpec {
+	return substitute(t, bindings)
+}
+
+// NamedType builds a named type such as Pair<number, string>.
+func NamedType(name string, args ...*TypeSpec) *TypeSpec {
+	return namedType(name, args...)
+}
+
+// UnionType builds the union of members.
+func UnionType(members ...*TypeSpec) *TypeSpec {
+	return unionType(members...)
+}
+
+// NullableType builds ?elem.
+func NullableType(elem *TypeSpec) *TypeSpec {
+	return nullableType(elem)
+}
+
+// FnType builds the type of a function from its parameter and return
+// types.
+func FnType(params []*TypeSpec, ret *TypeSpec) *TypeSpec {
+	return fnType(params, ret)
+}
@@ -1,21 +1,25 @@
 package runtime
 
-type ValueType string
-
-const (
-	AnyType          ValueType = "any"
-	NullType         ValueType = "null"
-	StringType       ValueType = "string"
-	NumberType       ValueType = "number"
-	BooleanType      ValueType = "boolean"
-	StructType       ValueType = "struct"
-	NativeFnType     ValueType = "native-fn"
-	FunctionType     ValueType = "function"
-	ArrayType        ValueType = "array"
-	ReturnType       ValueType = "return"
-	VarType          ValueType = "variable"
-	ArrayElementType ValueType = "array-element"
-	BreakType        ValueType = "break"
+// The types of the builtin values. They are shared and must not be
+// modified.
+var (
+	AnyType          = namedType("any")
+	NullType         = namedType("null")
+	StringType       = namedType("string")
+	NumberType       = namedType("number")
+	BooleanType      = namedType("boolean")
+	StructType       = namedType("struct")
+	EnumType         = namedType("enum")
+	NativeFnType     = namedType("native-fn")
+	FunctionType     = namedType("function")
+	ArrayType        = namedType("array")
+	MapType          = namedType("map")
+	SetType          = namedType("set")
+	TupleType        = namedType("tuple")
+	ReturnType       = namedType("return")
+	VarType          = namedType("variable")
+	ArrayElementType = namedType("array-element")
+	BreakType        = namedType("break")
 )
 
 func MKNULL() RuntimeVal {
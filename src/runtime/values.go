@@ -9,13 +9,13 @@ import (
 )
 
 type RuntimeVal interface {
-	Type() ValueType
+	Type() *TypeSpec
 	Inspect() string
 }
 
 type Variable struct {
 	Value        RuntimeVal
-	ExpectedType ValueType
+	ExpectedType *TypeSpec
 	Constant     bool
 }
 
@@ -35,37 +35,108 @@ type String struct {
 
 type Array struct {
 	Elements    []RuntimeVal
-	ElementType ValueType
+	ElementType *TypeSpec
+	Frozen      bool
+}
+
+type MapEntry struct {
+	Key   RuntimeVal
+	Value RuntimeVal
+}
+
+// Map is shared by reference so that set and delete are visible to every
+// holder of the value. Order keeps the hashed keys in insertion order.
+type Map struct {
+	KeyType   *TypeSpec
+	ValueType *TypeSpec
+	Order     []string
+	Entries   map[string]MapEntry
+	Frozen    bool
+}
+
+// Set is shared by reference like Map. Elements are keyed by hashKey so
+// structurally equal values are stored once, which rules out arrays, maps
+// and sets and the values holding them, see hashKey.
+type Set struct {
+	ElementType *TypeSpec
+	Order       []string
+	Elements    map[string]RuntimeVal
+	Frozen      bool
+}
+
+type Tuple struct {
+	Elements []RuntimeVal
+}
+
+// Distinct holds a value converted to a distinct type, e.g. Email("a@b.c").
+type Distinct struct {
+	TypeName string
+	Value    RuntimeVal
 }
 
 type StructDef struct {
 	Name       string
-	Properties map[string]ValueType
+	TypeParams []string
+	Properties map[string]*TypeSpec
+	Order      []string // property names, in declaration order
+	Defaults   map[string]ast.Expr
+	Required   map[string]bool
+	Readonly   map[string]bool // only set when the struct is instantiated
+	Embedded   []string        // each is also a property named after the struct
+	Public     map[string]bool
 	Methods    map[string]Function
+	Env        *environment // defaults are evaluated where the struct was declared
 }
 
 type Struct struct {
 	Name       string
+	TypeArgs   []*TypeSpec
 	Properties map[string]RuntimeVal
+	Order      []string  // shared with the StructDef
+	Def        StructDef // the declaration, wherever the value ends up
+	Frozen     bool
+}
+
+type EnumVariant struct {
+	Name   string
+	Fields []Parameter
+}
+
+type EnumDef struct {
+	Name     string
+	Variants []EnumVariant
+}
+
+type Enum struct {
+	Def     EnumDef
+	Variant string
+	Values  []RuntimeVal
 }
 
 type Function struct {
 	Name       string
+	TypeParams []string
 	Parameters []Parameter
+	ReturnType *TypeSpec
 	Body       ast.BlockStmt
 	Env        *environment
 	NativeFn   NativeFunction
 }
 
+// Parameter of a user defined function. For variadic parameters Type is the
+// element type of the collected array.
 type Parameter struct {
-	Name string
-	Type ValueType
+	Name     string
+	Type     *TypeSpec
+	Default  ast.Expr
+	Variadic bool
 }
 
 type Break struct{}
 
 type Return struct {
 	Value RuntimeVal
+	Line  int
 }
 
 type NativeFunction struct {
@@ -74,7 +145,7 @@ type NativeFunction struct {
 
 type FunctionCall func([]RuntimeVal) RuntimeVal
 
-func (v Variable) Type() ValueType {
+func (v Variable) Type() *TypeSpec {
 	return VarType
 }
 
@@ -82,7 +153,7 @@ func (v Variable) Inspect() string {
 	return fmt.Sprintf("%s<%s>", VarType, v.ExpectedType)
 }
 
-func (n Number) Type() ValueType {
+func (n Number) Type() *TypeSpec {
 	return NumberType
 }
 
@@ -90,7 +161,7 @@ func (n Number) Inspect() string {
 	return fmt.Sprintf("%g", n.Value)
 }
 
-func (b Bool) Type() ValueType {
+func (b Bool) Type() *TypeSpec {
 	return BooleanType
 }
 
@@ -98,7 +169,7 @@ func (b Bool) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
-func (n Null) Type() ValueType {
+func (n Null) Type() *TypeSpec {
 	return NullType
 }
 
@@ -106,7 +177,7 @@ func (n Null) Inspect() string {
 	return "null"
 }
 
-func (s String) Type() ValueType {
+func (s String) Type() *TypeSpec {
 	return StringType
 }
 
@@ -114,40 +185,279 @@ func (s String) Inspect() string {
 	return s.Value
 }
 
-func (a Array) Type() ValueType {
-	return ValueType(fmt.Sprintf("array<%s>", a.ElementType))
+func (a Array) Type() *TypeSpec {
+	return namedType(ArrayType.Name, a.ElementType)
 }
 
 func (a Array) Inspect() string {
 	return fmt.Sprintf("array<%s>", a.Elements)
 }
 
-func (sd StructDef) Type() ValueType {
-	return ValueType(sd.Name)
+func NewMap(keyType *TypeSpec, valueType *TypeSpec) *Map {
+	return &Map{
+		KeyType:   keyType,
+		ValueType: valueType,
+		Order:     make([]string, 0),
+		Entries:   make(map[string]MapEntry),
+	}
+}
+
+func (m *Map) Type() *TypeSpec {
+	return namedType(MapType.Name, m.KeyType, m.ValueType)
+}
+
+func (m *Map) Inspect() string {
+	var entries []string
+	for _, entry := range m.OrderedEntries() {
+		entries = append(entries, fmt.Sprintf("%s: %s", entry.Key.Inspect(), entry.Value.Inspect()))
+	}
+	return fmt.Sprintf("map<%s>", strings.Join(entries, ", "))
+}
+
+func (m *Map) OrderedEntries() []MapEntry {
+	entries := make([]MapEntry, len(m.Order))
+	for i, hash := range m.Order {
+		entries[i] = m.Entries[hash]
+	}
+	return entries
+}
+
+func (m *Map) Get(key RuntimeVal) (RuntimeVal, bool) {
+	entry, exists := m.Entries[hashKey(key, "a map key")]
+	return entry.Value, exists
+}
+
+func (m *Map) Set(key RuntimeVal, value RuntimeVal) {
+	if !checkType(key.Type(), m.KeyType) || key.Type().Equal(NullType) {
+		panic(fmt.Sprintf("Map key type mismatch: expected %s got %s", m.KeyType, key.Type()))
+	}
+
+	if !checkType(value.Type(), m.ValueType) {
+		panic(fmt.Sprintf("Map value type mismatch: expected %s got %s", m.ValueType, value.Type()))
+	}
+
+	hash := hashKey(key, "a map key")
+	if _, exists := m.Entries[hash]; !exists {
+		m.Order = append(m.Order, hash)
+	}
+	m.Entries[hash] = MapEntry{Key: key, Value: value}
+}
+
+func (m *Map) Delete(key RuntimeVal) bool {
+	hash := hashKey(key, "a map key")
+	if _, exists := m.Entries[hash]; !exists {
+		return false
+	}
+
+	delete(m.Entries, hash)
+	for i, h := range m.Order {
+		if h == hash {
+			m.Order = append(m.Order[:i], m.Order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func NewSet(elementType *TypeSpec) *Set {
+	return &Set{
+		ElementType: elementType,
+		Order:       make([]string, 0),
+		Elements:    make(map[string]RuntimeVal),
+	}
+}
+
+func (s *Set) Type() *TypeSpec {
+	return namedType(SetType.Name, s.ElementType)
+}
+
+func (s *Set) Inspect() string {
+	var elements []string
+	for _, element := range s.OrderedElements() {
+		elements = append(elements, element.Inspect())
+	}
+	return fmt.Sprintf("set<%s>", strings.Join(elements, ", "))
+}
+
+func (s *Set) OrderedElements() []RuntimeVal {
+	elements := make([]RuntimeVal, len(s.Order))
+	for i, hash := range s.Order {
+		elements[i] = s.Elements[hash]
+	}
+	return elements
+}
+
+func (s *Set) Has(element RuntimeVal) bool {
+	_, exists := s.Elements[hashKey(element, "a set element")]
+	return exists
+}
+
+func (s *Set) Add(element RuntimeVal) bool {
+	if !checkType(element.Type(), s.ElementType) {
+		panic(fmt.Sprintf("Set element type mismatch: expected %s got %s", s.ElementType, element.Type()))
+	}
+
+	hash := hashKey(element, "a set element")
+	if _, exists := s.Elements[hash]; exists {
+		return false
+	}
+
+	s.Order = append(s.Order, hash)
+	s.Elements[hash] = element
+	return true
+}
+
+func (s *Set) Remove(element RuntimeVal) bool {
+	hash := hashKey(element, "a set element")
+	if _, exists := s.Elements[hash]; !exists {
+		return false
+	}
+
+	delete(s.Elements, hash)
+	for i, h := range s.Order {
+		if h == hash {
+			s.Order = append(s.Order[:i], s.Order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (t Tuple) Type() *TypeSpec {
+	types := make([]*TypeSpec, len(t.Elements))
+	for i, element := range t.Elements {
+		types[i] = element.Type()
+	}
+	return namedType(TupleType.Name, types...)
+}
+
+func (t Tuple) Inspect() string {
+	var elements []string
+	for _, element := range t.Elements {
+		elements = append(elements, element.Inspect())
+	}
+	return fmt.Sprintf("(%s)", strings.Join(elements, ", "))
+}
+
+func (d Distinct) Type() *TypeSpec {
+	return namedType(d.TypeName)
+}
+
+func (d Distinct) Inspect() string {
+	return d.Value.Inspect()
+}
+
+func (sd StructDef) Type() *TypeSpec {
+	return namedType(sd.Name)
 }
 
 func (sd StructDef) Inspect() string {
 	var properties []string
-	for name, propType := range sd.Properties {
-		properties = append(properties, fmt.Sprintf("(%s, %s)", name, propType))
+	for _, name := range sd.Order {
+		properties = append(properties, fmt.Sprintf("(%s, %s)", name, sd.Properties[name]))
 	}
 	return fmt.Sprintf("%s<%s>", sd.Name, strings.Join(properties, ", "))
 }
 
-func (s Struct) Type() ValueType {
-	return ValueType(s.Name)
+func (s Struct) Type() *TypeSpec {
+	return namedType(s.Name, s.TypeArgs...)
 }
 
 func (s Struct) Inspect() string {
 	var properties []string
-	for name, propVal := range s.Properties {
-		properties = append(properties, fmt.Sprintf("(%s, %s)", name, propVal.Type()))
+	for _, name := range s.Order {
+		properties = append(properties, fmt.Sprintf("(%s, %s)", name, s.Properties[name].Type()))
 	}
 	return fmt.Sprintf("%s<%s>", s.Name, strings.Join(properties, ", "))
 }
 
-func (f Function) Type() ValueType {
-	return ValueType(FunctionType)
+func (ed EnumDef) Type() *TypeSpec {
+	return EnumType
+}
+
+func (ed EnumDef) Inspect() string {
+	var variants []string
+	for _, variant := range ed.Variants {
+		variants = append(variants, variant.Name)
+	}
+	return fmt.Sprintf("%s<%s>", ed.Name, strings.Join(variants, ", "))
+}
+
+func (ed EnumDef) lookupVariant(name string) (EnumVariant, bool) {
+	for _, variant := range ed.Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return EnumVariant{}, false
+}
+
+func (ed EnumDef) construct(variantName string, args []RuntimeVal) RuntimeVal {
+	variant, exists := ed.lookupVariant(variantName)
+	if !exists {
+		panic(fmt.Sprintf("Variant %s not found in enum %s", variantName, ed.Name))
+	}
+
+	if len(args) != len(variant.Fields) {
+		panic(fmt.Sprintf("Incorrect number of fields for %s.%s: expected %d, got %d", ed.Name, variantName, len(variant.Fields), len(args)))
+	}
+
+	for i, field := range variant.Fields {
+		if !checkType(args[i].Type(), field.Type) {
+			panic(fmt.Sprintf("Type mismatch for field %s in %s.%s: expected %s got %s", field.Name, ed.Name, variantName, field.Type, args[i].Type()))
+		}
+	}
+
+	return Enum{Def: ed, Variant: variantName, Values: args}
+}
+
+func (e Enum) Type() *TypeSpec {
+	return namedType(e.Def.Name)
+}
+
+func (e Enum) Inspect() string {
+	if len(e.Values) == 0 {
+		return fmt.Sprintf("%s.%s", e.Def.Name, e.Variant)
+	}
+
+	var values []string
+	for _, val := range e.Values {
+		values = append(values, val.Inspect())
+	}
+	return fmt.Sprintf("%s.%s(%s)", e.Def.Name, e.Variant, strings.Join(values, ", "))
+}
+
+func (e Enum) field(name string) (RuntimeVal, bool) {
+	variant, _ := e.Def.lookupVariant(e.Variant)
+	for i, field := range variant.Fields {
+		if field.Name == name {
+			return e.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Type describes the signature, e.g. fn(number, ...string): boolean. Type
+// parameters of generic functions are reported as any.
+func (f Function) Type() *TypeSpec {
+	bindings := bindTypeParams(f.TypeParams, make(map[string]*TypeSpec))
+	params := make([]*TypeSpec, len(f.Parameters))
+
+	for i, param := range f.Parameters {
+		params[i] = substitute(param.Type, bindings)
+		if param.Variadic {
+			variadic := *params[i]
+			variadic.Variadic = true
+			params[i] = &variadic
+		}
+	}
+
+	ret := f.ReturnType
+	if ret == nil {
+		ret = AnyType
+	}
+
+	return fnType(params, substitute(ret, bindings))
 }
 
 func (f Function) Inspect() string {
@@ -158,7 +468,7 @@ func (f Function) Inspect() string {
 	return fmt.Sprintf("%s<%s>", f.Name, strings.Join(params, ", "))
 }
 
-func (r Return) Type() ValueType {
+func (r Return) Type() *TypeSpec {
 	return ReturnType
 }
 
@@ -166,7 +476,7 @@ func (r Return) Inspect() string {
 	return fmt.Sprintf("return<%s>", r.Value)
 }
 
-func (b Break) Type() ValueType {
+func (b Break) Type() *TypeSpec {
 	return BreakType
 }
 
@@ -174,7 +484,7 @@ func (b Break) Inspect() string {
 	return "break"
 }
 
-func (n NativeFunction) Type() ValueType {
+func (n NativeFunction) Type() *TypeSpec {
 	return NativeFnType
 }
 
@@ -250,21 +560,119 @@ func (arr *Array) CallMethod(methodName string, args ...RuntimeVal) RuntimeVal {
 		if len(args) != 1 {
 			panic("append method expects exactly 1 argument")
 		}
-		if arr.ElementType != args[0].Type() {
-			panic("")
+		if !checkType(args[0].Type(), arr.ElementType) {
+			panic(fmt.Sprintf("Cannot append %s to %s", args[0].Type(), arr.Type()))
 		}
-		newArr := append(arr.Elements, args[0])
+		// append copies the elements so arrays appended to the same array,
+		// frozen ones included, never share them
+		newArr := append(arr.Elements[:len(arr.Elements):len(arr.Elements)], args[0])
 		arr.Elements = newArr
-		arr.ElementType = args[0].Type()
 		return Array{Elements: newArr, ElementType: arr.ElementType}
 	case "pop":
 		if len(arr.Elements) == 0 {
 			panic("pop method cannot be called on an empty array")
 		}
 		newArr := arr.Elements[:len(arr.Elements)-1]
+		if arr.Frozen {
+			// the result can be modified, it must not share the frozen elements
+			newArr = append([]RuntimeVal(nil), newArr...)
+		}
 		arr.Elements = newArr
 		return Array{Elements: newArr, ElementType: arr.ElementType}
 	default:
 		panic(fmt.Sprintf("Method %s not found for type Array", methodName))
 	}
 }
+
+func (m *Map) CallMethod(methodName string, args ...RuntimeVal) RuntimeVal {
+	switch methodName {
+	case "length":
+		return MKNUM(float64(len(m.Order)))
+	case "get":
+		if len(args) != 1 {
+			panic("get method expects exactly 1 argument")
+		}
+		if value, exists := m.Get(args[0]); exists {
+			return value
+		}
+		return MKNULL()
+	case "set":
+		if len(args) != 2 {
+			panic("set method expects exactly 2 arguments")
+		}
+		m.Set(args[0], args[1])
+		return args[1]
+	case "has":
+		if len(args) != 1 {
+			panic("has method expects exactly 1 argument")
+		}
+		_, exists := m.Get(args[0])
+		return MKBOOL(exists)
+	case "delete":
+		if len(args) != 1 {
+			panic("delete method expects exactly 1 argument")
+		}
+		return MKBOOL(m.Delete(args[0]))
+	case "keys":
+		keys := make([]RuntimeVal, 0, len(m.Order))
+		for _, entry := range m.OrderedEntries() {
+			keys = append(keys, entry.Key)
+		}
+		return Array{Elements: keys, ElementType: m.KeyType}
+	case "values":
+		values := make([]RuntimeVal, 0, len(m.Order))
+		for _, entry := range m.OrderedEntries() {
+			values = append(values, entry.Value)
+		}
+		return Array{Elements: values, ElementType: m.ValueType}
+	default:
+		panic(fmt.Sprintf("Method %s not found for type Map", methodName))
+	}
+}
+
+func (s *Set) CallMethod(methodName string, args ...RuntimeVal) RuntimeVal {
+	switch methodName {
+	case "length":
+		return MKNUM(float64(len(s.Order)))
+	case "add":
+		if len(args) != 1 {
+			panic("add method expects exactly 1 argument")
+		}
+		return MKBOOL(s.Add(args[0]))
+	case "remove":
+		if len(args) != 1 {
+			panic("remove method expects exactly 1 argument")
+		}
+		return MKBOOL(s.Remove(args[0]))
+	case "has":
+		if len(args) != 1 {
+			panic("has method expects exactly 1 argument")
+		}
+		return MKBOOL(s.Has(args[0]))
+	case "values":
+		return Array{Elements: s.OrderedElements(), ElementType: s.ElementType}
+	case "union", "intersection", "difference":
+		if len(args) != 1 {
+			panic(fmt.Sprintf("%s method expects exactly 1 argument", methodName))
+		}
+		other, ok := args[0].(*Set)
+		if !ok {
+			panic(fmt.Sprintf("%s method argument must be a set", methodName))
+		}
+
+		result := NewSet(s.ElementType)
+		for _, element := range s.OrderedElements() {
+			if methodName == "union" || (methodName == "intersection") == other.Has(element) {
+				result.Add(element)
+			}
+		}
+		if methodName == "union" {
+			for _, element := range other.OrderedElements() {
+				result.Add(element)
+			}
+		}
+		return result
+	default:
+		panic(fmt.Sprintf("Method %s not found for type Set", methodName))
+	}
+}